@@ -0,0 +1,550 @@
+package depinj
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ConfigSource represents a source of configuration values.
+type ConfigSource interface {
+	// LookupConfig looks up the configuration value by the given key, e.g.
+	// `server.port`. The value returned could be either a string, which
+	// will be parsed, or a structured value decoded from JSON/YAML/TOML,
+	// such as a bool, a number, a []interface{} or a map[string]interface{}.
+	// It returns false if the configuration value doesn't exist.
+	LookupConfig(key string) (value interface{}, ok bool)
+}
+
+// ConfigSourceFunc is the function type of ConfigSource.
+type ConfigSourceFunc func(key string) (value interface{}, ok bool)
+
+var _ ConfigSource = ConfigSourceFunc(nil)
+
+// LookupConfig implements ConfigSource.LookupConfig.
+func (csf ConfigSourceFunc) LookupConfig(key string) (interface{}, bool) { return csf(key) }
+
+// ConfigMap is a ConfigSource of structured configuration values. A key is
+// looked up as is first, and then as a path of nested maps separated by `.`,
+// e.g. key `server.port` matches `{"server": {"port": 8080}}`.
+type ConfigMap map[string]interface{}
+
+var _ ConfigSource = ConfigMap(nil)
+
+// LoadConfigMap decodes a ConfigMap from the given data with the given
+// unmarshal function, e.g. json.Unmarshal, yaml.Unmarshal or toml.Unmarshal.
+func LoadConfigMap(data []byte, unmarshal func([]byte, interface{}) error) (ConfigMap, error) {
+	var configMap ConfigMap
+
+	if err := unmarshal(data, &configMap); err != nil {
+		return nil, fmt.Errorf("depinj: config map load failed: %w", err)
+	}
+
+	return configMap, nil
+}
+
+// ReadConfigMapFile reads the file with the given name and decodes a
+// ConfigMap from the content with the given unmarshal function.
+// See LoadConfigMap for details.
+func ReadConfigMapFile(fileName string, unmarshal func([]byte, interface{}) error) (ConfigMap, error) {
+	data, err := ioutil.ReadFile(fileName)
+
+	if err != nil {
+		return nil, fmt.Errorf("depinj: config map read failed; fileName=%q: %w", fileName, err)
+	}
+
+	return LoadConfigMap(data, unmarshal)
+}
+
+// LookupConfig implements ConfigSource.LookupConfig.
+func (cm ConfigMap) LookupConfig(key string) (interface{}, bool) {
+	if value, ok := cm[key]; ok {
+		return value, true
+	}
+
+	var value interface{} = map[string]interface{}(cm)
+
+	for _, name := range strings.Split(key, ".") {
+		var ok bool
+
+		switch m := value.(type) {
+		case map[string]interface{}:
+			value, ok = m[name]
+		case map[interface{}]interface{}:
+			value, ok = m[name]
+		case ConfigMap:
+			value, ok = m[name]
+		}
+
+		if !ok {
+			return nil, false
+		}
+	}
+
+	return value, true
+}
+
+// EnvConfigSource is a ConfigSource which looks up configuration values from
+// the environment variables. The name of the environment variable for a key
+// is the prefix followed by the key in upper case, with characters other than
+// letters and digits replaced with `_`, e.g. the environment variable for key
+// `server.port` is `APP_SERVER_PORT` if the prefix is `APP_`.
+type EnvConfigSource struct {
+	Prefix string
+}
+
+var _ ConfigSource = EnvConfigSource{}
+
+// LookupConfig implements ConfigSource.LookupConfig.
+func (ecs EnvConfigSource) LookupConfig(key string) (interface{}, bool) {
+	value, ok := os.LookupEnv(ecs.EnvName(key))
+
+	if !ok {
+		return nil, false
+	}
+
+	return value, true
+}
+
+// EnvName returns the name of the environment variable for the given key.
+func (ecs EnvConfigSource) EnvName(key string) string {
+	return makeEnvName(ecs.Prefix, key)
+}
+
+// FlagConfigSource is a ConfigSource which looks up configuration values from
+// the command-line flags, the name of the flag for a key is the key itself.
+// Only the flags which have been set are taken into account, so that the
+// defaults in the config tags and the other config sources could take effect.
+type FlagConfigSource struct {
+	// FlagSet is the set of flags, flag.CommandLine is used if it's nil.
+	FlagSet *flag.FlagSet
+}
+
+var _ ConfigSource = FlagConfigSource{}
+
+// LookupConfig implements ConfigSource.LookupConfig.
+func (fcs FlagConfigSource) LookupConfig(key string) (interface{}, bool) {
+	flagSet := fcs.FlagSet
+
+	if flagSet == nil {
+		flagSet = flag.CommandLine
+	}
+
+	var value interface{}
+	ok := false
+
+	flagSet.Visit(func(flag *flag.Flag) {
+		if flag.Name == key {
+			value, ok = flag.Value.String(), true
+		}
+	})
+
+	return value, ok
+}
+
+type configEntry struct {
+	entry
+
+	// ParseField
+	Key          string
+	DefaultValue *string
+	IsOptional   bool
+
+	// Resolve1
+	Value reflect.Value
+}
+
+func (ce *configEntry) ParseField(fieldInfo *fieldInfo) (bool, error) {
+	args, ok := ce.entry.ParseField(fieldInfo, "config")
+
+	if !ok {
+		return false, nil
+	}
+
+	if fieldInfo.Descriptor.PkgPath != "" {
+		return false, fmt.Errorf("%w: field unexported; configEntryPath=%q",
+			ErrBadConfigEntry, ce.Path)
+	}
+
+	ce.Key = args[0]
+
+	if ce.Key == "" {
+		return false, fmt.Errorf("%w: missing argument `key`; configEntryPath=%q",
+			ErrBadConfigEntry, ce.Path)
+	}
+
+	var unknownArg string
+	ce.DefaultValue, ce.IsOptional, unknownArg = parseConfigArgs(args[1:])
+
+	if unknownArg != "" {
+		return false, fmt.Errorf("%w: unknown argument; configEntryPath=%q arg=%q",
+			ErrBadConfigEntry, ce.Path, unknownArg)
+	}
+
+	return true, nil
+}
+
+// parseConfigArgs parses the arguments following the key of a config tag,
+// and returns the first unknown argument, if any.
+func parseConfigArgs(args []string) (*string, bool, string) {
+	var defaultValue *string
+	isOptional := false
+
+	for i, arg := range args {
+		if value := strings.TrimPrefix(arg, "default="); value != arg {
+			// the default value may contain commas, e.g. `default=a,b,c`
+			value = strings.Join(append([]string{value}, args[i+1:]...), ",")
+			defaultValue = &value
+			break
+		}
+
+		if arg == "optional" {
+			isOptional = true
+			continue
+		}
+
+		return nil, false, arg
+	}
+
+	return defaultValue, isOptional, ""
+}
+
+func (ce *configEntry) Resolve1(context *resolution12Context) error {
+	rawValue, ok := context.LookupConfig(ce.Key)
+
+	if !ok {
+		if ce.DefaultValue != nil {
+			rawValue = *ce.DefaultValue
+		} else if ce.IsOptional {
+			ce.Value = reflect.Zero(ce.FieldType)
+			return nil
+		} else {
+			return fmt.Errorf("%w: config value not found; configEntryPath=%q key=%q",
+				ErrBadConfigEntry, ce.Path, ce.Key)
+		}
+	}
+
+	value := reflect.New(ce.FieldType).Elem()
+
+	if err := decodeConfigValue(rawValue, value, ce.Key); err != nil {
+		var notFoundErr *configValueNotFoundError
+
+		if errors.As(err, &notFoundErr) {
+			return fmt.Errorf("%w: config value not found; configEntryPath=%q key=%q",
+				ErrBadConfigEntry, ce.Path, notFoundErr.Key)
+		}
+
+		return fmt.Errorf("%w: config value decode failed; configEntryPath=%q key=%q fieldType=%q: %v",
+			ErrBadConfigEntry, ce.Path, ce.Key, ce.FieldType, err)
+	}
+
+	ce.Value = value
+	return nil
+}
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// configValueNotFoundError is the error of a required field of a structure
+// missing in the config value, with the full key of the field.
+type configValueNotFoundError struct {
+	Key string
+}
+
+func (cvnfe *configValueNotFoundError) Error() string {
+	return fmt.Sprintf("config value not found; key=%q", cvnfe.Key)
+}
+
+func decodeConfigValue(rawValue interface{}, value reflect.Value, key string) error {
+	if str, ok := rawValue.(string); ok {
+		return decodeConfigString(str, value, key)
+	}
+
+	if rawValue == nil {
+		value.Set(reflect.Zero(value.Type()))
+		return nil
+	}
+
+	rawValue2 := reflect.ValueOf(rawValue)
+
+	switch value.Kind() {
+	case reflect.Ptr:
+		elemValue := reflect.New(value.Type().Elem())
+
+		if err := decodeConfigValue(rawValue, elemValue.Elem(), key); err != nil {
+			return err
+		}
+
+		value.Set(elemValue)
+		return nil
+	case reflect.Interface:
+		if !rawValue2.Type().AssignableTo(value.Type()) {
+			break
+		}
+
+		value.Set(rawValue2)
+		return nil
+	case reflect.Bool:
+		if rawValue2.Kind() != reflect.Bool {
+			break
+		}
+
+		value.SetBool(rawValue2.Bool())
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		if !isNumberKind(rawValue2.Kind()) {
+			break
+		}
+
+		return decodeConfigString(formatNumber(rawValue2), value, key)
+	case reflect.Slice:
+		if rawValue2.Kind() != reflect.Slice {
+			break
+		}
+
+		n := rawValue2.Len()
+		sliceValue := reflect.MakeSlice(value.Type(), n, n)
+
+		for i := 0; i < n; i++ {
+			if err := decodeConfigValue(rawValue2.Index(i).Interface(), sliceValue.Index(i), key+"."+strconv.Itoa(i)); err != nil {
+				return fmt.Errorf("index %d: %w", i, err)
+			}
+		}
+
+		value.Set(sliceValue)
+		return nil
+	case reflect.Map:
+		if rawValue2.Kind() != reflect.Map {
+			break
+		}
+
+		mapValue := reflect.MakeMapWithSize(value.Type(), rawValue2.Len())
+
+		for iter := rawValue2.MapRange(); iter.Next(); {
+			keyValue := reflect.New(value.Type().Key()).Elem()
+
+			if err := decodeConfigString(fmt.Sprint(iter.Key().Interface()), keyValue, key); err != nil {
+				return fmt.Errorf("key %v: %w", iter.Key().Interface(), err)
+			}
+
+			elemValue := reflect.New(value.Type().Elem()).Elem()
+
+			if err := decodeConfigValue(iter.Value().Interface(), elemValue, fmt.Sprintf("%s.%v", key, iter.Key().Interface())); err != nil {
+				return fmt.Errorf("key %v: %w", iter.Key().Interface(), err)
+			}
+
+			mapValue.SetMapIndex(keyValue, elemValue)
+		}
+
+		value.Set(mapValue)
+		return nil
+	case reflect.Struct:
+		if rawValue2.Kind() != reflect.Map {
+			break
+		}
+
+		return decodeConfigStructure(rawValue2, value, key)
+	}
+
+	return fmt.Errorf("type mismatch (expected `%v`, got `%T`)", value.Type(), rawValue)
+}
+
+// decodeConfigStructure decodes the fields of the given structure from the
+// given map. The fields are matched by their names, or the keys in their
+// config tags, case-insensitively. The options of the config tags are taken as
+// the config entries do, i.e. a tagged field missing in the map is set to the
+// default value, left as is if optional, or fails the decoding otherwise.
+func decodeConfigStructure(rawValue reflect.Value, value reflect.Value, key string) error {
+	structureType := value.Type()
+
+	for i, n := 0, structureType.NumField(); i < n; i++ {
+		descriptor := structureType.Field(i)
+
+		if descriptor.PkgPath != "" {
+			continue
+		}
+
+		name := descriptor.Name
+		var defaultValue *string
+		isRequired := false
+
+		if tag, ok := descriptor.Tag.Lookup("config"); ok {
+			args := strings.Split(tag, ",")
+
+			if args[0] != "" {
+				name = args[0]
+			}
+
+			var isOptional bool
+			var unknownArg string
+			defaultValue, isOptional, unknownArg = parseConfigArgs(args[1:])
+
+			if unknownArg != "" {
+				return fmt.Errorf("field %s: unknown argument %q", descriptor.Name, unknownArg)
+			}
+
+			isRequired = defaultValue == nil && !isOptional
+		}
+
+		fieldKey := key + "." + name
+		var fieldRawValue interface{}
+		ok := false
+
+		for iter := rawValue.MapRange(); iter.Next(); {
+			if strings.EqualFold(fmt.Sprint(iter.Key().Interface()), name) {
+				fieldRawValue, ok = iter.Value().Interface(), true
+				break
+			}
+		}
+
+		if !ok {
+			if defaultValue != nil {
+				fieldRawValue = *defaultValue
+			} else if isRequired {
+				return &configValueNotFoundError{fieldKey}
+			} else {
+				continue
+			}
+		}
+
+		if err := decodeConfigValue(fieldRawValue, value.Field(i), fieldKey); err != nil {
+			return fmt.Errorf("field %s: %w", descriptor.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func decodeConfigString(str string, value reflect.Value, key string) error {
+	if reflect.PtrTo(value.Type()).Implements(textUnmarshalerType) {
+		return value.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(str))
+	}
+
+	if value.Type() == durationType {
+		duration, err := time.ParseDuration(str)
+
+		if err != nil {
+			return err
+		}
+
+		value.SetInt(int64(duration))
+		return nil
+	}
+
+	switch value.Kind() {
+	case reflect.Ptr:
+		elemValue := reflect.New(value.Type().Elem())
+
+		if err := decodeConfigString(str, elemValue.Elem(), key); err != nil {
+			return err
+		}
+
+		value.Set(elemValue)
+		return nil
+	case reflect.String:
+		value.SetString(str)
+		return nil
+	case reflect.Bool:
+		b, err := strconv.ParseBool(str)
+
+		if err != nil {
+			return err
+		}
+
+		value.SetBool(b)
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(str, 0, value.Type().Bits())
+
+		if err != nil {
+			return err
+		}
+
+		value.SetInt(i)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u, err := strconv.ParseUint(str, 0, value.Type().Bits())
+
+		if err != nil {
+			return err
+		}
+
+		value.SetUint(u)
+		return nil
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(str, value.Type().Bits())
+
+		if err != nil {
+			return err
+		}
+
+		value.SetFloat(f)
+		return nil
+	case reflect.Slice:
+		if str == "" {
+			value.Set(reflect.MakeSlice(value.Type(), 0, 0))
+			return nil
+		}
+
+		strs := strings.Split(str, ",")
+		sliceValue := reflect.MakeSlice(value.Type(), len(strs), len(strs))
+
+		for i, str := range strs {
+			if err := decodeConfigString(strings.TrimSpace(str), sliceValue.Index(i), key+"."+strconv.Itoa(i)); err != nil {
+				return fmt.Errorf("index %d: %w", i, err)
+			}
+		}
+
+		value.Set(sliceValue)
+		return nil
+	case reflect.Interface:
+		if !reflect.TypeOf(str).AssignableTo(value.Type()) {
+			break
+		}
+
+		value.Set(reflect.ValueOf(str))
+		return nil
+	case reflect.Map, reflect.Struct:
+		var rawValue interface{}
+
+		if err := json.Unmarshal([]byte(str), &rawValue); err != nil {
+			return err
+		}
+
+		return decodeConfigValue(rawValue, value, key)
+	}
+
+	return fmt.Errorf("type mismatch (expected `%v`, got `string`)", value.Type())
+}
+
+func isNumberKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	default:
+		return false
+	}
+}
+
+func formatNumber(value reflect.Value) string {
+	switch value.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(value.Float(), 'f', -1, value.Type().Bits())
+	default:
+		return fmt.Sprint(value.Interface())
+	}
+}
@@ -0,0 +1,153 @@
+package depinj_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/depinj"
+)
+
+type serverConfig struct {
+	Host    string
+	Port    int           `config:"port"`
+	Timeout time.Duration `config:"timeout"`
+}
+
+type podJ1 struct {
+	depinj.DummyPod
+	Port     int           `config:"server.port,default=8080"`
+	Timeout  time.Duration `config:"server.read_timeout,default=3s"`
+	Tags     []string      `config:"server.tags,default=a,b"`
+	Server   serverConfig  `config:"server"`
+	Verbose  bool          `config:"verbose"`
+	Level    uint8         `config:"level"`
+	Optional *float64      `config:"optional,optional"`
+	T        *testing.T
+}
+
+func (p *podJ1) SetUp(context.Context) error {
+	assert.Equal(p.T, 9090, p.Port)
+	assert.Equal(p.T, 3*time.Second, p.Timeout)
+	assert.Equal(p.T, []string{"a", "b"}, p.Tags)
+	assert.Equal(p.T, serverConfig{Host: "localhost", Port: 9090, Timeout: time.Minute}, p.Server)
+	assert.True(p.T, p.Verbose)
+	assert.Equal(p.T, uint8(3), p.Level)
+	assert.Nil(p.T, p.Optional)
+	return nil
+}
+
+func TestConfigEntries(t *testing.T) {
+	configMap, err := depinj.LoadConfigMap([]byte(`{"server": {"host": "localhost", "port": 9090, "timeout": "1m"}, "level": 1}`), json.Unmarshal)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, "APP_SERVER_PORT", depinj.EnvConfigSource{Prefix: "APP_"}.EnvName("server.port"))
+	os.Setenv("APP_VERBOSE", "true")
+	defer os.Unsetenv("APP_VERBOSE")
+	flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+	flagSet.Int("level", 2, "")
+	err = flagSet.Parse([]string{"-level=3"})
+	assert.NoError(t, err)
+	var pp depinj.PodPool
+	pp.AddConfigSource(depinj.FlagConfigSource{FlagSet: flagSet})
+	pp.AddConfigSource(depinj.EnvConfigSource{Prefix: "APP_"})
+	pp.AddConfigSource(configMap)
+	p := &podJ1{T: t}
+	err = pp.AddPod(p)
+	assert.NoError(t, err)
+	err = pp.SetUp(context.Background())
+	assert.NoError(t, err)
+	pp.TearDown()
+	assert.Equal(t, 0, p.Port)
+}
+
+type tlsConfig struct {
+	Cert    string        `config:"cert"`
+	Timeout time.Duration `config:"timeout,default=5s"`
+	Ciphers []string      `config:"ciphers,optional"`
+}
+
+type httpConfig struct {
+	Addr string `config:"addr,default=:80"`
+	TLS  tlsConfig
+}
+
+type podJ2 struct {
+	depinj.DummyPod
+	HTTP httpConfig `config:"http"`
+	T    *testing.T
+}
+
+func (p *podJ2) SetUp(context.Context) error {
+	assert.Equal(p.T, httpConfig{Addr: ":80", TLS: tlsConfig{Cert: "a.pem", Timeout: 5 * time.Second}}, p.HTTP)
+	return nil
+}
+
+func TestNestedConfigEntries(t *testing.T) {
+	var pp depinj.PodPool
+	pp.AddConfigSource(depinj.ConfigMap{"http": map[string]interface{}{"tls": map[string]interface{}{"cert": "a.pem"}}})
+	pp.MustAddPod(&podJ2{T: t})
+	err := pp.SetUp(context.Background())
+	assert.NoError(t, err)
+	pp.TearDown()
+
+	pp = depinj.PodPool{}
+	pp.AddConfigSource(depinj.ConfigMap{"http": map[string]interface{}{"tls": map[string]interface{}{}}})
+	pp.MustAddPod(&podJ2{T: t})
+	err = pp.SetUp(context.Background())
+	assert.True(t, errors.Is(err, depinj.ErrBadConfigEntry))
+	assert.EqualError(t, err, "depinj: bad config entry: config value not found; configEntryPath=\"depinj_test.podJ2.HTTP\" key=\"http.TLS.cert\"")
+}
+
+type podK1 struct {
+	depinj.DummyPod
+	Port int `config:""`
+}
+
+type podK2 struct {
+	depinj.DummyPod
+	Port int `config:"port,required"`
+}
+
+type podK3 struct {
+	depinj.DummyPod
+	port int `config:"port"`
+}
+
+type podK4 struct {
+	depinj.DummyPod
+	Port int `config:"port"`
+}
+
+type podK5 struct {
+	depinj.DummyPod
+	Port int `config:"port,default=abc"`
+}
+
+func TestConfigEntryFailed(t *testing.T) {
+	for _, tt := range []struct {
+		Pod    depinj.Pod
+		ErrMsg string
+	}{
+		{&podK1{}, "depinj: bad config entry: missing argument `key`; configEntryPath=\"depinj_test.podK1.Port\""},
+		{&podK2{}, "depinj: bad config entry: unknown argument; configEntryPath=\"depinj_test.podK2.Port\" arg=\"required\""},
+		{&podK3{}, "depinj: bad config entry: field unexported; configEntryPath=\"depinj_test.podK3.port\""},
+		{&podK4{}, "depinj: bad config entry: config value not found; configEntryPath=\"depinj_test.podK4.Port\" key=\"port\""},
+		{&podK5{}, "depinj: bad config entry: config value decode failed; configEntryPath=\"depinj_test.podK5.Port\" key=\"port\" fieldType=\"int\": strconv.ParseInt: parsing \"abc\": invalid syntax"},
+	} {
+		var pp depinj.PodPool
+		err := pp.AddPod(tt.Pod)
+		if err == nil {
+			err = pp.SetUp(context.Background())
+		}
+		assert.True(t, errors.Is(err, depinj.ErrBadConfigEntry))
+		assert.EqualError(t, err, tt.ErrMsg)
+	}
+}
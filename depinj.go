@@ -7,28 +7,52 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
-	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
+
+	"github.com/roy2220/depinj/internal/tagsyntax"
 )
 
-// PodPool represents a set of pods.
+// PodPool represents a set of pods. It's safe for concurrent use, however the
+// operations of the pool can't overlap, e.g. calling AddPod while the pool is
+// being set up, even from within Pod.SetUp, fails with ErrPodPoolBusy.
 type PodPool struct {
-	pods     []pod
-	firstPod *pod
-	lastPod  *pod
+	mutex              sync.Mutex
+	state              PodPoolState
+	isBusy             bool
+	pods               []*pod
+	hasRootPods        bool
+	refLinkResolvers   []RefLinkResolver
+	configSources      []ConfigSource
+	strictMode         StrictMode
+	healthCheckTimeout time.Duration
+	debugMode          bool
+	redactor           Redactor
+	numResolvedPods    int
+	firstPod           *pod
+	lastPod            *pod
+	warnings           []error
+}
+
+// State returns the current state of the pool. While the pool is busy, it
+// returns the state before the operation in progress.
+func (pp *PodPool) State() PodPoolState {
+	pp.mutex.Lock()
+	defer pp.mutex.Unlock()
+	return pp.state
 }
 
 // AddPod adds the given pod to the pool.
 func (pp *PodPool) AddPod(rawPod Pod) error {
-	var pod pod
+	pod := new(pod)
 
 	if err := pod.ParseRaw(rawPod); err != nil {
 		return err
 	}
 
-	pp.pods = append(pp.pods, pod)
-	return nil
+	return pp.addPod(pod)
 }
 
 // MustAddPod adds the given pod to the pool, it panics if any error occurs.
@@ -38,24 +62,431 @@ func (pp *PodPool) MustAddPod(rawPod Pod) {
 	}
 }
 
-// SetUp sets up all the pods in the pool.
-func (pp *PodPool) SetUp(ctx context.Context) (returnedErr error) {
+// AddNamedPod adds the given pod to the pool with the given name, which
+// prefixes the paths of the entries and the ref ids (not ref links) of the
+// import/export/filter entries in the pod, in the form of `<name>/<path>` and
+// `<name>/<ref id>`, so that a reusable pod type could be instantiated more
+// than once, e.g. per shard or per region, with unambiguous paths in errors.
+// The name also names the pod in errors, taking precedence over
+// PodNamer.PodName.
+func (pp *PodPool) AddNamedPod(name string, rawPod Pod) error {
+	if name == "" {
+		return fmt.Errorf("%w: empty pod name; podType=%q", ErrInvalidPod, reflect.TypeOf(rawPod))
+	}
+
+	pod := &pod{Name: name}
+
+	if err := pod.ParseRaw(rawPod); err != nil {
+		return err
+	}
+
+	return pp.addPod(pod)
+}
+
+// MustAddNamedPod adds the given pod to the pool with the given name, it
+// panics if any error occurs.
+func (pp *PodPool) MustAddNamedPod(name string, rawPod Pod) {
+	if err := pp.AddNamedPod(name, rawPod); err != nil {
+		panic(err)
+	}
+}
+
+// AddRootPod adds the given pod to the pool as a root pod. Once any root
+// pod is added, only the root pods and the pods reachable from the root pods
+// through import/filter entries are resolved, set up and torn down, the
+// other pods in the pool are skipped.
+func (pp *PodPool) AddRootPod(rawPod Pod) error {
+	pod := new(pod)
+
+	if err := pod.ParseRaw(rawPod); err != nil {
+		return err
+	}
+
+	pod.IsRoot = true
+	return pp.addPod(pod)
+}
+
+// MustAddRootPod adds the given pod to the pool as a root pod, it panics if
+// any error occurs.
+func (pp *PodPool) MustAddRootPod(rawPod Pod) {
+	if err := pp.AddRootPod(rawPod); err != nil {
+		panic(err)
+	}
+}
+
+func (pp *PodPool) addPod(pod *pod) error {
+	pp.mutex.Lock()
+	defer pp.mutex.Unlock()
+
+	if pp.isBusy {
+		return fmt.Errorf("%w; podType=%q", ErrPodPoolBusy, reflect.TypeOf(pod.Raw))
+	}
+
+	pp.pods = append(pp.pods, pod)
+	pp.hasRootPods = pp.hasRootPods || pod.IsRoot
+	pp.invalidateResolution()
+	return nil
+}
+
+func (pp *PodPool) addPods(pods []*pod, modulePath string) error {
+	pp.mutex.Lock()
+	defer pp.mutex.Unlock()
+
+	if pp.isBusy {
+		return fmt.Errorf("%w; modulePath=%q", ErrPodPoolBusy, modulePath)
+	}
+
+	pp.pods = append(pp.pods, pods...)
+	pp.invalidateResolution()
+	return nil
+}
+
+// AddRefLinkResolver adds the given ref link resolver to the pool. The ref
+// link resolvers of the pool are tried in order, after Pod.ResolveRefLink
+// fails to resolve a ref link.
+func (pp *PodPool) AddRefLinkResolver(refLinkResolver RefLinkResolver) {
+	pp.mutex.Lock()
+	defer pp.mutex.Unlock()
+	pp.refLinkResolvers = append(pp.refLinkResolvers, refLinkResolver)
+	pp.invalidateResolution()
+}
+
+// AddConfigSource adds the given config source to the pool. The config
+// sources of the pool are tried in order to look up the values for the
+// config entries.
+func (pp *PodPool) AddConfigSource(configSource ConfigSource) {
+	pp.mutex.Lock()
+	defer pp.mutex.Unlock()
+	pp.configSources = append(pp.configSources, configSource)
+	pp.invalidateResolution()
+}
+
+// SetStrictMode sets the strict mode of the pool, which determines how
+// unused export entries and unused pods are reported during the setup.
+func (pp *PodPool) SetStrictMode(strictMode StrictMode) {
+	pp.mutex.Lock()
+	defer pp.mutex.Unlock()
+	pp.strictMode = strictMode
+	pp.invalidateResolution()
+}
+
+// Warnings returns the warnings reported during the last setup of the pool.
+// Warnings are only reported in StrictModeWarn. It returns nil while the pool
+// is busy.
+func (pp *PodPool) Warnings() []error {
+	pp.mutex.Lock()
+	defer pp.mutex.Unlock()
+
+	if pp.isBusy {
+		return nil
+	}
+
+	return pp.warnings
+}
+
+// SetUp sets up all the pods in the pool. Once the pool has been set up,
+// calling SetUp again only resolves the pods added since the last setup
+// against the live export entries and sets them up, the teardown order is
+// extended accordingly. The pods added after the setup can't filter the
+// export entries which have been set up. If the pods added since the last
+// setup fail to be resolved or set up, they are removed from the pool, and
+// the pods which have been set up stay live. A pool which has been torn down
+// can be set up again.
+func (pp *PodPool) SetUp(ctx context.Context) error {
+	state, err := pp.beginOperation()
+
+	if err != nil {
+		return err
+	}
+
+	defer func() { pp.endOperation(state) }()
+
+	if state == PodPoolStateSetUp {
+		return pp.setUpIncrementally(ctx)
+	}
+
 	if err := pp.resolve(); err != nil {
 		return err
 	}
 
-	pod := pp.firstPod
+	state = PodPoolStateResolved
+
+	if err := pp.setUpPods(ctx, pp.firstPod); err != nil {
+		return err
+	}
+
+	state = PodPoolStateSetUp
+	return nil
+}
+
+// MustSetUp sets up all the pods in the pool, it panics if any error occurs.
+func (pp *PodPool) MustSetUp(ctx context.Context) {
+	if err := pp.SetUp(ctx); err != nil {
+		panic(err)
+	}
+}
+
+// FilterRecords returns the records of the runs of the filter methods during
+// the last setup of the pool, in the order of runs. It returns nil while the
+// pool is busy.
+func (pp *PodPool) FilterRecords() []FilterRecord {
+	pp.mutex.Lock()
+	defer pp.mutex.Unlock()
+
+	if pp.isBusy {
+		return nil
+	}
+
+	var filterRecords []FilterRecord
+
+	for pod := pp.firstPod; pod != nil; pod = pod.Next {
+		for i := range pod.ExportEntries {
+			exportEntry := &pod.ExportEntries[i]
+			filterRecords = append(filterRecords, exportEntry.FilterRecords...)
+		}
+	}
+
+	return filterRecords
+}
+
+// TearDown tears down all the pods in the pool in a reverse order of setups.
+// Before any pod is torn down, BeforeAnyTearDownHook.BeforeAnyTearDown is called
+// for each pod in a reverse order of setups, with context.Background().
+// It does nothing if the pool hasn't been set up, has been torn down or is
+// busy, see TryTearDown for the errors.
+func (pp *PodPool) TearDown() {
+	pp.TryTearDown()
+}
+
+// TryTearDown tears down all the pods in the pool, as TearDown does. The pool
+// must have been set up, it fails with ErrPodPoolNotSetUp otherwise, or with
+// ErrPodPoolTornDown if the pool has been torn down.
+func (pp *PodPool) TryTearDown() error {
+	state, err := pp.beginOperation()
+
+	if err != nil {
+		return err
+	}
+
+	defer func() { pp.endOperation(state) }()
+
+	switch state {
+	case PodPoolStateSetUp:
+	case PodPoolStateTornDown:
+		return ErrPodPoolTornDown
+	default:
+		return ErrPodPoolNotSetUp
+	}
+
+	ctx := context.Background()
+
+	for pod := pp.lastPod; pod != nil; pod = pod.Prev {
+		pod.BeforeAnyTearDown(ctx)
+	}
+
+	for pod := pp.lastPod; pod != nil; pod = pod.Prev {
+		pod.TearDown()
+	}
+
+	state = PodPoolStateTornDown
+	return nil
+}
+
+// MustTearDown tears down all the pods in the pool, it panics if any error
+// occurs.
+func (pp *PodPool) MustTearDown() {
+	if err := pp.TryTearDown(); err != nil {
+		panic(err)
+	}
+}
+
+// Restart restarts the given pod in the pool and the pods which depend on it
+// directly or indirectly, i.e. the pods importing the exports of the pod and the
+// pods whose exports are filtered by the pod. The config entries of these pods
+// are resolved again, then these pods are torn down in a reverse order of
+// setups and set up again in the order of setups, the other pods in the pool
+// are left untouched. The pool must have been set up. If the config entries
+// fail to be resolved, no pod is restarted. If any pod fails to be set up
+// again, the whole pool is torn down, as TearDown does, and turns into
+// PodPoolStateTornDown, since the other pods may depend on the failed ones.
+func (pp *PodPool) Restart(ctx context.Context, rawPod Pod) error {
+	state, err := pp.beginOperation()
+
+	if err != nil {
+		return err
+	}
+
+	defer func() { pp.endOperation(state) }()
+
+	if state != PodPoolStateSetUp {
+		return ErrPodPoolNotSetUp
+	}
+
+	isTornDown, err := pp.restart(ctx, rawPod)
+
+	if isTornDown {
+		state = PodPoolStateTornDown
+	}
+
+	return err
+}
+
+// MustRestart restarts the given pod in the pool and the pods which depend on
+// it, it panics if any error occurs.
+func (pp *PodPool) MustRestart(ctx context.Context, rawPod Pod) {
+	if err := pp.Restart(ctx, rawPod); err != nil {
+		panic(err)
+	}
+}
+
+func (pp *PodPool) restart(ctx context.Context, rawPod Pod) (bool, error) {
+	targetPod, ok := pp.findPod(rawPod)
+
+	if !ok {
+		return false, fmt.Errorf("%w; podType=%q", ErrPodNotFound, reflect.TypeOf(rawPod))
+	}
+
+	dependents := map[*pod]struct{}{targetPod: {}}
+	targetPod.CollectDependents(dependents)
+	_, configSources, _ := pp.resolutionSettings()
+	context := new(resolution12Context).Init(nil, configSources)
+
+	for pod2 := pp.firstPod; pod2 != nil; pod2 = pod2.Next {
+		if _, ok := dependents[pod2]; !ok {
+			continue
+		}
+
+		if err := pod2.Resolve1ConfigEntries(context); err != nil {
+			return false, err
+		}
+	}
+
+	for pod2 := pp.lastPod; pod2 != nil; pod2 = pod2.Prev {
+		if _, ok := dependents[pod2]; ok {
+			pod2.BeforeAnyTearDown(ctx)
+		}
+	}
+
+	for pod2 := pp.lastPod; pod2 != nil; pod2 = pod2.Prev {
+		if _, ok := dependents[pod2]; ok {
+			pod2.TearDown()
+		}
+	}
+
+	// hookedPods are the pods restarted whose after-all-setup hooks have been
+	// called, which are paired with the before-any-teardown hooks.
+	hookedPods := make(map[*pod]struct{})
+
+	if err := pp.setUpDependents(ctx, dependents, hookedPods); err != nil {
+		for pod2 := pp.lastPod; pod2 != nil; pod2 = pod2.Prev {
+			_, isDependent := dependents[pod2]
+			_, isHooked := hookedPods[pod2]
+
+			if !isDependent || isHooked {
+				pod2.BeforeAnyTearDown(ctx)
+			}
+		}
+
+		for pod2 := pp.lastPod; pod2 != nil; pod2 = pod2.Prev {
+			pod2.TearDown()
+		}
+
+		return true, err
+	}
+
+	return false, nil
+}
+
+func (pp *PodPool) setUpDependents(ctx context.Context, dependents map[*pod]struct{}, hookedPods map[*pod]struct{}) error {
+	podDescriber := pp.podDescriber()
+
+	for pod2 := pp.firstPod; pod2 != nil; pod2 = pod2.Next {
+		if _, ok := dependents[pod2]; !ok {
+			continue
+		}
+
+		if err := pod2.SetUp(ctx, podDescriber); err != nil {
+			return err
+		}
+	}
+
+	for pod2 := pp.firstPod; pod2 != nil; pod2 = pod2.Next {
+		if _, ok := dependents[pod2]; !ok {
+			continue
+		}
+
+		if err := pod2.AfterAllSetUp(ctx, podDescriber); err != nil {
+			return err
+		}
+
+		hookedPods[pod2] = struct{}{}
+	}
+
+	return nil
+}
+
+func (pp *PodPool) setUpIncrementally(ctx context.Context) error {
+	if pp.numResolvedPods == len(pp.pods) {
+		return nil
+	}
+
+	lastPod := pp.lastPod
+
+	if err := pp.resolveIncrementally(); err != nil {
+		pp.rollBackIncrementally(lastPod)
+		return err
+	}
+
+	firstPod := pp.firstPod
+
+	if lastPod != nil {
+		firstPod = lastPod.Next
+	}
+
+	if err := pp.setUpPods(ctx, firstPod); err != nil {
+		pp.rollBackIncrementally(lastPod)
+		return err
+	}
+
+	pp.numResolvedPods = len(pp.pods)
+	return nil
+}
+
+func (pp *PodPool) setUpPods(ctx context.Context, firstPod *pod) (returnedErr error) {
+	if firstPod == nil {
+		return nil
+	}
+
+	podDescriber := pp.podDescriber()
+	stopPod := firstPod.Prev
+	pod := firstPod
 
 	defer func() {
 		if returnedErr != nil {
-			for pod = pod.Prev; pod != nil; pod = pod.Prev {
+			if pod == nil {
+				pod = pp.lastPod
+			} else {
+				pod = pod.Prev
+			}
+
+			for ; pod != stopPod; pod = pod.Prev {
 				pod.TearDown()
 			}
 		}
 	}()
 
 	for ; pod != nil; pod = pod.Next {
-		if err := pod.SetUp(ctx); err != nil {
+		if err := pod.SetUp(ctx, podDescriber); err != nil {
+			return err
+		}
+	}
+
+	for pod2 := firstPod; pod2 != nil; pod2 = pod2.Next {
+		if err := pod2.AfterAllSetUp(ctx, podDescriber); err != nil {
+			for pod2 = pod2.Prev; pod2 != stopPod; pod2 = pod2.Prev {
+				pod2.BeforeAnyTearDown(ctx)
+			}
+
 			return err
 		}
 	}
@@ -63,60 +494,474 @@ func (pp *PodPool) SetUp(ctx context.Context) (returnedErr error) {
 	return nil
 }
 
-// MustSetUp sets up all the pods in the pool, it panics if any error occurs.
-func (pp *PodPool) MustSetUp(ctx context.Context) {
-	if err := pp.SetUp(ctx); err != nil {
+// rollBackIncrementally removes the pods added since the last setup, otherwise
+// every later setup would fail the same way.
+func (pp *PodPool) rollBackIncrementally(lastPod *pod) {
+	pp.unlinkPodsAfter(lastPod)
+
+	for i := pp.numResolvedPods; i < len(pp.pods); i++ {
+		pp.pods[i] = nil
+	}
+
+	pp.pods = pp.pods[:pp.numResolvedPods]
+	pp.hasRootPods = false
+
+	for _, pod := range pp.pods {
+		pp.hasRootPods = pp.hasRootPods || pod.IsRoot
+	}
+}
+
+func (pp *PodPool) unlinkPodsAfter(lastPod *pod) {
+	if lastPod == nil {
+		pp.firstPod = nil
+	} else {
+		lastPod.Next = nil
+	}
+
+	pp.lastPod = lastPod
+
+	for _, pod := range pp.pods {
+		if !pod.IsSetUp {
+			pod.Unlink()
+		}
+	}
+}
+
+// RemovePod removes the given pod from the pool. If the pod has been set up,
+// it's torn down before removal. It fails if any other pod which has been set
+// up imports the exports of the pod, see RemovePodCascade.
+func (pp *PodPool) RemovePod(rawPod Pod) error {
+	return pp.removePod(rawPod, false)
+}
+
+// MustRemovePod removes the given pod from the pool, it panics if any error
+// occurs.
+func (pp *PodPool) MustRemovePod(rawPod Pod) {
+	if err := pp.RemovePod(rawPod); err != nil {
 		panic(err)
 	}
 }
 
-// TearDown tears down all the pods in the pool in a reverse order of setups.
-func (pp *PodPool) TearDown() {
-	for pod := pp.lastPod; pod != nil; pod = pod.Prev {
-		pod.TearDown()
+// RemovePodCascade removes the given pod from the pool along with the pods
+// which import the exports of the pod directly or indirectly. The pods which
+// have been set up are torn down in a reverse order of setups before removal.
+func (pp *PodPool) RemovePodCascade(rawPod Pod) error {
+	return pp.removePod(rawPod, true)
+}
+
+// MustRemovePodCascade removes the given pod from the pool along with the pods
+// which depend on it, it panics if any error occurs.
+func (pp *PodPool) MustRemovePodCascade(rawPod Pod) {
+	if err := pp.RemovePodCascade(rawPod); err != nil {
+		panic(err)
+	}
+}
+
+func (pp *PodPool) removePod(rawPod Pod, cascade bool) error {
+	state, err := pp.beginOperation()
+
+	if err != nil {
+		return err
+	}
+
+	defer func() { pp.endOperation(state) }()
+	var targetPod *pod
+
+	for _, pod := range pp.pods {
+		if pod.Raw == rawPod {
+			targetPod = pod
+			break
+		}
+	}
+
+	if targetPod == nil {
+		return fmt.Errorf("%w; podType=%q", ErrPodNotFound, reflect.TypeOf(rawPod))
+	}
+
+	removedPods := map[*pod]struct{}{targetPod: {}}
+
+	if cascade {
+		targetPod.CollectImporters(removedPods)
+	} else if importEntryPaths := targetPod.ImportEntryPathsOfLiveImporters(); len(importEntryPaths) >= 1 {
+		return fmt.Errorf("%w; podType=%q importEntryPaths=%q",
+			ErrPodInUse, reflect.TypeOf(rawPod), importEntryPaths)
+	}
+
+	if state == PodPoolStateSetUp {
+		ctx := context.Background()
+
+		for pod := pp.lastPod; pod != nil; pod = pod.Prev {
+			if _, ok := removedPods[pod]; ok {
+				pod.BeforeAnyTearDown(ctx)
+			}
+		}
+	}
+
+	for pod := pp.lastPod; pod != nil; pod = pod.Prev {
+		if _, ok := removedPods[pod]; ok {
+			pod.TearDown()
+		}
+	}
+
+	for pod := pp.firstPod; pod != nil; {
+		nextPod := pod.Next
+
+		if _, ok := removedPods[pod]; ok {
+			if pod.Prev == nil {
+				pp.firstPod = pod.Next
+			} else {
+				pod.Prev.Next = pod.Next
+			}
+
+			if pod.Next == nil {
+				pp.lastPod = pod.Prev
+			} else {
+				pod.Next.Prev = pod.Prev
+			}
+		}
+
+		pod = nextPod
+	}
+
+	pods := pp.pods[:0]
+	numResolvedPods := pp.numResolvedPods
+	pp.hasRootPods = false
+
+	for i, pod := range pp.pods {
+		if _, ok := removedPods[pod]; ok {
+			pod.Unlink()
+
+			if i < pp.numResolvedPods {
+				numResolvedPods--
+			}
+
+			continue
+		}
+
+		pp.hasRootPods = pp.hasRootPods || pod.IsRoot
+		pods = append(pods, pod)
+	}
+
+	for i := len(pods); i < len(pp.pods); i++ {
+		pp.pods[i] = nil
+	}
+
+	pp.pods = pods
+	pp.numResolvedPods = numResolvedPods
+
+	if state == PodPoolStateResolved {
+		state = PodPoolStateNew
+	}
+
+	return nil
+}
+
+func (pp *PodPool) beginOperation() (PodPoolState, error) {
+	pp.mutex.Lock()
+	defer pp.mutex.Unlock()
+
+	if pp.isBusy {
+		return 0, ErrPodPoolBusy
+	}
+
+	pp.isBusy = true
+	return pp.state, nil
+}
+
+func (pp *PodPool) endOperation(state PodPoolState) {
+	pp.mutex.Lock()
+	defer pp.mutex.Unlock()
+	pp.state = state
+	pp.isBusy = false
+}
+
+func (pp *PodPool) invalidateResolution() {
+	if pp.state == PodPoolStateResolved {
+		pp.state = PodPoolStateNew
+	}
+}
+
+func (pp *PodPool) resolutionSettings() ([]RefLinkResolver, []ConfigSource, StrictMode) {
+	pp.mutex.Lock()
+	defer pp.mutex.Unlock()
+	return pp.refLinkResolvers, pp.configSources, pp.strictMode
+}
+
+func (pp *PodPool) findPod(rawPod Pod) (*pod, bool) {
+	for pod := pp.firstPod; pod != nil; pod = pod.Next {
+		if pod.Raw == rawPod {
+			return pod, true
+		}
+	}
+
+	return nil, false
+}
+
+func (pp *PodPool) resolve() error {
+	refLinkResolvers, configSources, strictMode := pp.resolutionSettings()
+
+	{
+		context := new(resolution12Context).Init(refLinkResolvers, configSources)
+
+		if pp.hasRootPods {
+			// the errors of the pods are deferred until the pods are reached,
+			// since the pods unreachable from the root pods are skipped.
+			pp.resolve1Deferredly(context)
+
+			for _, pod := range pp.pods {
+				if !pod.IsRoot {
+					continue
+				}
+
+				if err := pod.Resolve2Reachably(context); err != nil {
+					return err
+				}
+			}
+		} else {
+			for _, pod := range pp.pods {
+				if err := pod.Resolve1(context); err != nil {
+					return err
+				}
+			}
+
+			for _, pod := range pp.pods {
+				if err := pod.Resolve2(context); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	{
+		context := new(resolution3Context).Init()
+
+		for _, pod := range pp.pods {
+			if pp.hasRootPods && !pod.IsRoot {
+				continue
+			}
+
+			if err := pod.Resolve3(context); err != nil {
+				return err
+			}
+		}
+
+		if err := pp.checkStrictly(context.FirstPod(), strictMode); err != nil {
+			return err
+		}
+
+		pp.firstPod = context.FirstPod()
+		pp.lastPod = context.LastPod()
+	}
+
+	pp.numResolvedPods = len(pp.pods)
+	return nil
+}
+
+// resolve1Deferredly resolves the import/export/filter entries of the pods,
+// including the filter entries in Resolve2, since they are the edges from the
+// export entries to the filtering pods, which are followed by
+// pod.Resolve2Reachably. The errors of the pods are deferred until the pods are
+// reached.
+func (pp *PodPool) resolve1Deferredly(context *resolution12Context) {
+	for _, pod := range pp.pods {
+		context.DeferPodError(pod, pod.Resolve1Entries(context))
+	}
+
+	for _, pod := range pp.pods {
+		if context.PodError(pod) == nil {
+			context.DeferPodError(pod, pod.Resolve2FilterEntries(context))
+		}
+	}
+}
+
+func (pp *PodPool) resolveIncrementally() error {
+	newPods := pp.pods[pp.numResolvedPods:]
+	refLinkResolvers, configSources, _ := pp.resolutionSettings()
+
+	{
+		context := new(resolution12Context).Init(refLinkResolvers, configSources)
+		pp.resolve1Deferredly(context)
+
+		for _, pod := range newPods {
+			if err := pod.Resolve2Reachably(context); err != nil {
+				return err
+			}
+		}
+	}
+
+	{
+		context := new(resolution3Context).Init()
+		context.AddResolvedPods(pp.firstPod, pp.lastPod)
+		lastPod := pp.lastPod
+
+		for _, pod := range newPods {
+			if err := pod.Resolve3(context); err != nil {
+				return err
+			}
+		}
+
+		pp.firstPod = context.FirstPod()
+		pp.lastPod = context.LastPod()
+		firstPod := pp.firstPod
+
+		if lastPod != nil {
+			firstPod = lastPod.Next
+		}
+
+		for pod := firstPod; pod != nil; pod = pod.Next {
+			if err := pod.CheckFilterEntriesIncrementally(); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (pp *PodPool) checkStrictly(firstPod *pod, strictMode StrictMode) error {
+	pp.warnings = nil
+
+	if strictMode == StrictModeOff {
+		return nil
 	}
-}
 
-func (pp *PodPool) resolve() error {
-	{
-		context := new(resolution12Context).Init()
+	filterIDs := make(map[string]struct{})
 
-		for i := range pp.pods {
-			pod := &pp.pods[i]
+	for pod := firstPod; pod != nil; pod = pod.Next {
+		for i := range pod.FilterEntries {
+			filterEntry := &pod.FilterEntries[i]
+			filterIDs[filterEntry.Path] = struct{}{}
 
-			if err := pod.Resolve1(context); err != nil {
-				return err
+			if filterEntry.ID != "" {
+				filterIDs[filterEntry.ID] = struct{}{}
 			}
 		}
+	}
 
-		for i := range pp.pods {
-			pod := &pp.pods[i]
+	for pod := firstPod; pod != nil; pod = pod.Next {
+		for i := range pod.FilterEntries {
+			filterEntry := &pod.FilterEntries[i]
 
-			if err := pod.Resolve2(context); err != nil {
-				return err
+			for _, ids := range [...][]string{filterEntry.BeforeIDs, filterEntry.AfterIDs} {
+				for _, id := range ids {
+					if _, ok := filterIDs[id]; ok {
+						continue
+					}
+
+					err := fmt.Errorf("%w: unknown filter id; filterEntryPath=%q filterID=%q", ErrBadFilterEntry, filterEntry.Path, id)
+
+					if strictMode == StrictModeError {
+						return err
+					}
+
+					pp.warnings = append(pp.warnings, err)
+				}
+			}
+
+			if filterEntry.IsWildcard() && len(filterEntry.ExportEntries) == 0 {
+				err := fmt.Errorf("%w: no export entry matched; filterEntryPath=%q", ErrUnusedFilterEntry, filterEntry.Path)
+
+				if strictMode == StrictModeError {
+					return err
+				}
+
+				pp.warnings = append(pp.warnings, err)
 			}
 		}
-	}
 
-	{
-		context := new(resolution3Context).Init()
+		for i := range pod.ExportEntries {
+			exportEntry := &pod.ExportEntries[i]
 
-		for i := range pp.pods {
-			pod := &pp.pods[i]
+			if len(exportEntry.ImportEntries) == 0 && len(exportEntry.FilterEntries) == 0 {
+				err := fmt.Errorf("%w: no import/filter entry; exportEntryPath=%q", ErrUnusedExportEntry, exportEntry.Path)
 
-			if err := pod.Resolve3(context); err != nil {
-				return err
+				if strictMode == StrictModeError {
+					return err
+				}
+
+				pp.warnings = append(pp.warnings, err)
 			}
 		}
 
-		pp.firstPod = context.FirstPod()
-		pp.lastPod = context.LastPod()
+		if !pod.HasDependents() && !pod.HasSideEffects() {
+			err := fmt.Errorf("%w: no dependent pod; podType=%q", ErrUnusedPod, reflect.TypeOf(pod.Raw))
+
+			if strictMode == StrictModeError {
+				return err
+			}
+
+			pp.warnings = append(pp.warnings, err)
+		}
 	}
 
 	return nil
 }
 
+// StrictMode represents the way to report unused export entries, unused
+// filter entries and unused pods. An export entry is unused if no
+// import/filter entry consumes it. A wildcard filter entry is unused if it
+// matches no export entry. A pod is unused if no other pod depends on it and
+// it has no side effects (see SideEffector). The `before`/`after` constraints
+// of the filter entries naming no filter entry are reported as well.
+type StrictMode int
+
+const (
+	// StrictModeOff disables the reports.
+	StrictModeOff StrictMode = iota
+
+	// StrictModeWarn reports as warnings, see PodPool.Warnings.
+	StrictModeWarn
+
+	// StrictModeError reports as an error, which fails the setup.
+	StrictModeError
+)
+
+// PodPoolState represents the state of a pool.
+type PodPoolState int
+
+const (
+	// PodPoolStateNew is the state of a pool which hasn't been resolved, or
+	// has been changed since the last resolution.
+	PodPoolStateNew PodPoolState = iota
+
+	// PodPoolStateResolved is the state of a pool whose pods have been
+	// resolved but not set up, see PodPool.Graph.
+	PodPoolStateResolved
+
+	// PodPoolStateSetUp is the state of a pool whose pods have been set up.
+	PodPoolStateSetUp
+
+	// PodPoolStateTornDown is the state of a pool whose pods have been torn
+	// down.
+	PodPoolStateTornDown
+)
+
+// String returns the name of the state.
+func (pps PodPoolState) String() string {
+	switch pps {
+	case PodPoolStateNew:
+		return "new"
+	case PodPoolStateResolved:
+		return "resolved"
+	case PodPoolStateSetUp:
+		return "set up"
+	case PodPoolStateTornDown:
+		return "torn down"
+	default:
+		return "PodPoolState(" + strconv.Itoa(int(pps)) + ")"
+	}
+}
+
 // Pod represents a container for dependency injection.
+//
+// The import/export/filter/config entries are tagged fields of the pod
+// structure, or of the structures inlined into it, i.e. the embedded
+// structures, the embedded pointers to structures with entries, and the
+// structure (pointer) fields tagged with `depinj:"inline"`, e.g. the import
+// entry `DB` in the inline field `Deps` of the pod `app.Server` has the path
+// `app.Server.Deps.DB`. The nil pointers to the inlined structures are set to
+// new structures when the pod is added to a pool, whereas the other embedded
+// pointers, e.g. `*sync.Mutex`, are left untouched.
 type Pod interface {
 	// ResolveRefLink resolves the given ref link into a ref id.
 	// It returns false if the ref link is unresolvable. When it
@@ -136,6 +981,49 @@ type Pod interface {
 	TearDown()
 }
 
+// Namespacer is an optional interface of Pod. The namespace of a pod prefixes
+// the ref ids (not ref links) of the import/export/filter entries in the pod,
+// in the form of `<namespace>/<ref id>`, so that a reusable pod type could be
+// instantiated more than once with different ref ids.
+type Namespacer interface {
+	// RefIDNamespace returns the namespace of the pod.
+	RefIDNamespace() string
+}
+
+// Namespace is the default implementation of Namespacer.
+// It could be embedded in a pod to specify the namespace of the pod.
+type Namespace string
+
+var _ Namespacer = Namespace("")
+
+// RefIDNamespace returns the namespace.
+func (n Namespace) RefIDNamespace() string { return string(n) }
+
+// AfterAllSetUpHook is an optional interface of Pod.
+type AfterAllSetUpHook interface {
+	// AfterAllSetUp is called for each pod in the order of setups, once all
+	// the pods in PodPool have been set up, e.g. to register the pod with
+	// service discovery. If it fails, the setup of PodPool fails.
+	AfterAllSetUp(ctx context.Context) (err error)
+}
+
+// BeforeAnyTearDownHook is an optional interface of Pod.
+type BeforeAnyTearDownHook interface {
+	// BeforeAnyTearDown is called for each pod in a reverse order of setups,
+	// before any pod in PodPool is torn down, e.g. to deregister the pod from
+	// service discovery. It's only called if all the pods in PodPool have been
+	// set up and AfterAllSetUpHook.AfterAllSetUp has been called.
+	BeforeAnyTearDown(ctx context.Context)
+}
+
+// SideEffector is an optional interface of Pod. A pod no other pod depends
+// on, e.g. a server, should implement SideEffector to report it has side
+// effects, so that it's not considered unused in strict mode.
+type SideEffector interface {
+	// HasSideEffects returns true if the pod has side effects.
+	HasSideEffects() bool
+}
+
 // DummyPod is the dummy implementation of Pod.
 // It could be embedded as the default implementation of Pod.
 type DummyPod struct{}
@@ -153,28 +1041,92 @@ func (DummyPod) TearDown() {}
 
 // Sentinel errors
 var (
-	ErrInvalidPod            = errors.New("depinj: invalid pod")
-	ErrBadImportEntry        = errors.New("depinj: bad import entry")
-	ErrBadExportEntry        = errors.New("depinj: bad export entry")
-	ErrBadFilterEntry        = errors.New("depinj: bad filter entry")
-	ErrPodCircularDependency = errors.New("depinj: pod circular dependency")
+	ErrInvalidPod               = errors.New("depinj: invalid pod")
+	ErrInvalidModule            = errors.New("depinj: invalid module")
+	ErrBadImportEntry           = errors.New("depinj: bad import entry")
+	ErrBadExportEntry           = errors.New("depinj: bad export entry")
+	ErrBadFilterEntry           = errors.New("depinj: bad filter entry")
+	ErrBadConfigEntry           = errors.New("depinj: bad config entry")
+	ErrPodCircularDependency    = errors.New("depinj: pod circular dependency")
+	ErrFilterCircularConstraint = errors.New("depinj: filter circular constraint")
+	ErrUnusedExportEntry        = errors.New("depinj: unused export entry")
+	ErrUnusedFilterEntry        = errors.New("depinj: unused filter entry")
+	ErrUnusedPod                = errors.New("depinj: unused pod")
+	ErrPodNotFound              = errors.New("depinj: pod not found")
+	ErrPodInUse                 = errors.New("depinj: pod in use")
+	ErrPodPoolNotSetUp          = errors.New("depinj: pod pool not set up")
+	ErrPodPoolTornDown          = errors.New("depinj: pod pool torn down")
+	ErrPodPoolBusy              = errors.New("depinj: pod pool busy")
+
+	// ErrFilterSkipped could be returned (or wrapped) by a filter method to
+	// indicate the filter is skipped, which doesn't fail the setup.
+	ErrFilterSkipped = errors.New("depinj: filter skipped")
 )
 
+// FilterError is the error returned when a filter method fails.
+type FilterError struct {
+	FilterEntryPath string
+	ExportEntryPath string
+	Err             error
+}
+
+var _ error = (*FilterError)(nil)
+
+// Error implements error.Error.
+func (fe *FilterError) Error() string {
+	return fmt.Sprintf("depinj: filter function failed; filterEntryPath=%q exportEntryPath=%q: %v",
+		fe.FilterEntryPath, fe.ExportEntryPath, fe.Err)
+}
+
+// Unwrap returns the error returned by the filter method.
+func (fe *FilterError) Unwrap() error {
+	return fe.Err
+}
+
+// FilterTarget describes the export entry a filter method is applied to.
+// A filter method could take a FilterTarget as the parameter following
+// the context, which is useful for wildcard filters.
+type FilterTarget struct {
+	RefID           string
+	ExportEntryPath string
+}
+
+// FilterRecord records a run of a filter method.
+type FilterRecord struct {
+	FilterEntryPath string
+	ExportEntryPath string
+	IsSkipped       bool
+}
+
 const (
 	resolution3PodEntered resolution3PodState = 1 + iota
 	resolution3PodLeft
 )
 
 type pod struct {
+	// AddNamedPod
+	Name string
+
+	// AddModule
+	Module *moduleScope
+
 	// ParseRaw
 	Raw           Pod
 	ImportEntries []importEntry
 	ExportEntries []exportEntry
 	FilterEntries []filterEntry
+	ConfigEntries []configEntry
+
+	// AddRootPod
+	IsRoot bool
 
 	// Resolve3
 	Next *pod
 	Prev *pod
+
+	// SetUp
+	IsSetUp       bool
+	SetUpDuration time.Duration
 }
 
 func (p *pod) ParseRaw(raw Pod) error {
@@ -195,18 +1147,26 @@ func (p *pod) ParseRaw(raw Pod) error {
 		return err
 	}
 
-	if len(p.ImportEntries)+len(p.ExportEntries)+len(p.FilterEntries) == 0 {
-		return fmt.Errorf("%w: no import/export/filter entry; podType=%q", ErrInvalidPod, value.Type())
+	if len(p.ImportEntries)+len(p.ExportEntries)+len(p.FilterEntries)+len(p.ConfigEntries) == 0 {
+		return fmt.Errorf("%w: no import/export/filter/config entry; podType=%q", ErrInvalidPod, value.Type())
 	}
 
 	return nil
 }
 
 func (p *pod) Resolve1(context *resolution12Context) error {
+	if err := p.Resolve1Entries(context); err != nil {
+		return err
+	}
+
+	return p.Resolve1ConfigEntries(context)
+}
+
+func (p *pod) Resolve1Entries(context *resolution12Context) error {
 	for i := range p.ImportEntries {
 		importEntry := &p.ImportEntries[i]
 
-		if err := importEntry.Resolve1(p); err != nil {
+		if err := importEntry.Resolve1(context, p); err != nil {
 			return err
 		}
 	}
@@ -222,7 +1182,19 @@ func (p *pod) Resolve1(context *resolution12Context) error {
 	for i := range p.FilterEntries {
 		filterEntry := &p.FilterEntries[i]
 
-		if err := filterEntry.Resolve1(p); err != nil {
+		if err := filterEntry.Resolve1(context, p); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *pod) Resolve1ConfigEntries(context *resolution12Context) error {
+	for i := range p.ConfigEntries {
+		configEntry := &p.ConfigEntries[i]
+
+		if err := configEntry.Resolve1(context); err != nil {
 			return err
 		}
 	}
@@ -231,6 +1203,14 @@ func (p *pod) Resolve1(context *resolution12Context) error {
 }
 
 func (p *pod) Resolve2(context *resolution12Context) error {
+	if err := p.Resolve2ImportEntries(context); err != nil {
+		return err
+	}
+
+	return p.Resolve2FilterEntries(context)
+}
+
+func (p *pod) Resolve2ImportEntries(context *resolution12Context) error {
 	for i := range p.ImportEntries {
 		importEntry := &p.ImportEntries[i]
 
@@ -239,6 +1219,10 @@ func (p *pod) Resolve2(context *resolution12Context) error {
 		}
 	}
 
+	return nil
+}
+
+func (p *pod) Resolve2FilterEntries(context *resolution12Context) error {
 	for i := range p.FilterEntries {
 		filterEntry := &p.FilterEntries[i]
 
@@ -250,65 +1234,330 @@ func (p *pod) Resolve2(context *resolution12Context) error {
 	return nil
 }
 
+func (p *pod) Resolve2Reachably(context *resolution12Context) error {
+	if !context.ReachPod(p) {
+		return nil
+	}
+
+	if err := context.PodError(p); err != nil {
+		return err
+	}
+
+	if err := p.Resolve1ConfigEntries(context); err != nil {
+		return err
+	}
+
+	if err := p.Resolve2ImportEntries(context); err != nil {
+		return err
+	}
+
+	for i := range p.ImportEntries {
+		importEntry := &p.ImportEntries[i]
+
+		if err := importEntry.ExportEntry.Pod.Resolve2Reachably(context); err != nil {
+			return err
+		}
+	}
+
+	for i := range p.ExportEntries {
+		exportEntry := &p.ExportEntries[i]
+
+		for _, filterEntry := range exportEntry.FilterEntries {
+			if err := filterEntry.Pod.Resolve2Reachably(context); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
 func (p *pod) Resolve3(context *resolution3Context) error {
 	return p.doResolve3(context, "")
 }
 
-func (p *pod) SetUp(ctx context.Context) (returnedErr error) {
+func (p *pod) SetUp(ctx context.Context, podDescriber podDescriber) (returnedErr error) {
 	for i := range p.ImportEntries {
 		importEntry := &p.ImportEntries[i]
 		exportEntry := importEntry.ExportEntry
 		importEntry.FieldValue.Set(exportEntry.FieldValue)
 	}
 
-	if err := p.Raw.SetUp(ctx); err != nil {
-		return fmt.Errorf("depinj: pod setup failed; pod=%#v: %w", p.Raw, err)
+	for i := range p.ConfigEntries {
+		configEntry := &p.ConfigEntries[i]
+		configEntry.FieldValue.Set(configEntry.Value)
 	}
 
+	startTime := time.Now()
+	err := p.Raw.SetUp(ctx)
+	p.SetUpDuration = time.Since(startTime)
+
+	if err != nil {
+		return fmt.Errorf("depinj: pod setup failed; %s: %w", podDescriber.Describe(p.Raw, p.FullName()), err)
+	}
+
+	p.IsSetUp = true
+
 	defer func() {
 		if returnedErr != nil {
 			p.TearDown()
 		}
-	}()
+	}()
+
+	for i := range p.ExportEntries {
+		exportEntry := &p.ExportEntries[i]
+
+		for _, filterEntry := range exportEntry.FilterEntries {
+			filterEntry.FieldValue.Set(exportEntry.FieldValue.Addr())
+		}
+
+		exportEntry.FilterRecords = exportEntry.FilterRecords[:0]
+		exportEntry.FilterDuration = 0
+
+		for _, filterEntry := range exportEntry.FilterEntries {
+			startTime := time.Now()
+			err := filterEntry.Function(ctx, FilterTarget{
+				RefID:           exportEntry.RefID,
+				ExportEntryPath: exportEntry.Path,
+			})
+			duration := time.Since(startTime)
+			skipped := errors.Is(err, ErrFilterSkipped)
+
+			if err != nil && !skipped {
+				return &FilterError{
+					FilterEntryPath: filterEntry.Path,
+					ExportEntryPath: exportEntry.Path,
+					Err:             err,
+				}
+			}
+
+			exportEntry.FilterRecords = append(exportEntry.FilterRecords, FilterRecord{
+				FilterEntryPath: filterEntry.Path,
+				ExportEntryPath: exportEntry.Path,
+				IsSkipped:       skipped,
+			})
+			exportEntry.FilterDuration += duration
+		}
+	}
+
+	return nil
+}
+
+func (p *pod) AfterAllSetUp(ctx context.Context, podDescriber podDescriber) error {
+	hook, ok := p.Raw.(AfterAllSetUpHook)
+
+	if !ok {
+		return nil
+	}
+
+	if err := hook.AfterAllSetUp(ctx); err != nil {
+		return fmt.Errorf("depinj: pod after-all-setup hook failed; %s: %w", podDescriber.Describe(p.Raw, p.FullName()), err)
+	}
+
+	return nil
+}
+
+func (p *pod) BeforeAnyTearDown(ctx context.Context) {
+	if !p.IsSetUp {
+		return
+	}
+
+	if hook, ok := p.Raw.(BeforeAnyTearDownHook); ok {
+		hook.BeforeAnyTearDown(ctx)
+	}
+}
+
+func (p *pod) TearDown() {
+	if !p.IsSetUp {
+		return
+	}
+
+	p.IsSetUp = false
+	p.Raw.TearDown()
+
+	for i := range p.ImportEntries {
+		importEntry := &p.ImportEntries[i]
+		importEntry.FieldValue.Set(reflect.Zero(importEntry.FieldType))
+	}
+
+	for i := range p.ExportEntries {
+		exportEntry := &p.ExportEntries[i]
+		exportEntry.FieldValue.Set(reflect.Zero(exportEntry.FieldType))
+	}
+
+	for i := range p.FilterEntries {
+		filterEntry := &p.FilterEntries[i]
+		filterEntry.FieldValue.Set(reflect.Zero(filterEntry.FieldType))
+	}
+
+	for i := range p.ConfigEntries {
+		configEntry := &p.ConfigEntries[i]
+		configEntry.FieldValue.Set(reflect.Zero(configEntry.FieldType))
+	}
+}
+
+func (p *pod) CheckFilterEntriesIncrementally() error {
+	for i := range p.FilterEntries {
+		filterEntry := &p.FilterEntries[i]
+
+		for _, exportEntry := range filterEntry.ExportEntries {
+			if exportEntry.Pod.IsSetUp {
+				return fmt.Errorf("%w: export entry already set up; filterEntryPath=%q exportEntryPath=%q",
+					ErrBadFilterEntry, filterEntry.Path, exportEntry.Path)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (p *pod) Unlink() {
+	for i := range p.ImportEntries {
+		importEntry := &p.ImportEntries[i]
+
+		if exportEntry := importEntry.ExportEntry; exportEntry != nil {
+			exportEntry.ImportEntries = removeImportEntry(exportEntry.ImportEntries, importEntry)
+			importEntry.ExportEntry = nil
+		}
+	}
+
+	for i := range p.ExportEntries {
+		exportEntry := &p.ExportEntries[i]
+
+		for _, filterEntry := range exportEntry.FilterEntries {
+			filterEntry.ExportEntries = removeExportEntry(filterEntry.ExportEntries, exportEntry)
+		}
+
+		exportEntry.FilterEntries = nil
+	}
+
+	for i := range p.FilterEntries {
+		filterEntry := &p.FilterEntries[i]
+
+		for _, exportEntry := range filterEntry.ExportEntries {
+			exportEntry.FilterEntries = removeFilterEntry(exportEntry.FilterEntries, filterEntry)
+		}
+
+		filterEntry.ExportEntries = nil
+	}
+
+	p.Prev = nil
+	p.Next = nil
+}
+
+func (p *pod) CollectDependents(dependents map[*pod]struct{}) {
+	collect := func(dependent *pod) {
+		if _, ok := dependents[dependent]; ok {
+			return
+		}
+
+		dependents[dependent] = struct{}{}
+		dependent.CollectDependents(dependents)
+	}
+
+	for i := range p.ExportEntries {
+		exportEntry := &p.ExportEntries[i]
+
+		for _, importEntry := range exportEntry.ImportEntries {
+			collect(importEntry.Pod)
+		}
+	}
+
+	for i := range p.FilterEntries {
+		filterEntry := &p.FilterEntries[i]
+
+		for _, exportEntry := range filterEntry.ExportEntries {
+			collect(exportEntry.Pod)
+		}
+	}
+}
+
+func (p *pod) CollectImporters(importers map[*pod]struct{}) {
+	for i := range p.ExportEntries {
+		exportEntry := &p.ExportEntries[i]
+
+		for _, importEntry := range exportEntry.ImportEntries {
+			importer := importEntry.Pod
+
+			if _, ok := importers[importer]; ok {
+				continue
+			}
+
+			importers[importer] = struct{}{}
+			importer.CollectImporters(importers)
+		}
+	}
+}
+
+func (p *pod) ImportEntryPathsOfLiveImporters() []string {
+	var importEntryPaths []string
+
+	for i := range p.ExportEntries {
+		exportEntry := &p.ExportEntries[i]
+
+		for _, importEntry := range exportEntry.ImportEntries {
+			if importEntry.Pod != p && importEntry.Pod.IsSetUp {
+				importEntryPaths = append(importEntryPaths, importEntry.Path)
+			}
+		}
+	}
+
+	return importEntryPaths
+}
 
+func (p *pod) HasDependents() bool {
 	for i := range p.ExportEntries {
 		exportEntry := &p.ExportEntries[i]
 
-		for _, filterEntry := range exportEntry.FilterEntries {
-			filterEntry.FieldValue.Set(exportEntry.FieldValue.Addr())
+		if len(exportEntry.ImportEntries) >= 1 {
+			return true
 		}
+	}
 
-		for _, filterEntry := range exportEntry.FilterEntries {
-			if err := filterEntry.Function(ctx); err != nil {
-				return fmt.Errorf("depinj: filter function failed; pod=%#v: %w", p.Raw, err)
+	for i := range p.FilterEntries {
+		filterEntry := &p.FilterEntries[i]
+
+		for _, exportEntry := range filterEntry.ExportEntries {
+			if exportEntry.Pod != p {
+				return true
 			}
 		}
 	}
 
-	return nil
+	return false
 }
 
-func (p *pod) TearDown() {
-	p.Raw.TearDown()
-
-	for i := range p.ImportEntries {
-		importEntry := &p.ImportEntries[i]
-		importEntry.FieldValue.Set(reflect.Zero(importEntry.FieldType))
+func (p *pod) NamespaceRefID(refID string) string {
+	if refID == "" {
+		return ""
 	}
 
-	for i := range p.ExportEntries {
-		exportEntry := &p.ExportEntries[i]
-		exportEntry.FieldValue.Set(reflect.Zero(exportEntry.FieldType))
+	if namespacer, ok := p.Raw.(Namespacer); ok {
+		if namespace := namespacer.RefIDNamespace(); namespace != "" {
+			refID = namespace + "/" + refID
+		}
 	}
 
-	for i := range p.FilterEntries {
-		filterEntry := &p.FilterEntries[i]
-		filterEntry.FieldValue.Set(reflect.Zero(filterEntry.FieldType))
+	if p.Name != "" {
+		refID = p.Name + "/" + refID
 	}
+
+	return refID
+}
+
+// FullName returns the name of the pod prefixed with the module path, if any.
+func (p *pod) FullName() string {
+	return p.Module.JoinPath(p.Name)
+}
+
+func (p *pod) HasSideEffects() bool {
+	sideEffector, ok := p.Raw.(SideEffector)
+	return ok && sideEffector.HasSideEffects()
 }
 
 func (p *pod) parseStructure(parentFieldInfo *fieldInfo, structureValue reflect.Value) error {
 	fieldInfo := fieldInfo{
+		PodName:        p.FullName(),
 		Parent:         parentFieldInfo,
 		StructureValue: structureValue,
 		StructureType:  structureValue.Type(),
@@ -317,9 +1566,14 @@ func (p *pod) parseStructure(parentFieldInfo *fieldInfo, structureValue reflect.
 	for i, n := 0, fieldInfo.StructureType.NumField(); i < n; i++ {
 		fieldInfo.Descriptor = fieldInfo.StructureType.Field(i)
 
-		if fieldInfo.Descriptor.Anonymous && fieldInfo.Descriptor.Type.Kind() == reflect.Struct {
-			p.parseStructure(&fieldInfo, structureValue.Field(i))
+		if innerStructureValue, ok, err := fieldInfo.InlineStructure(); ok {
+			if err := p.parseStructure(&fieldInfo, innerStructureValue); err != nil {
+				return err
+			}
+
 			continue
+		} else if err != nil {
+			return err
 		}
 
 		var importEntry importEntry
@@ -348,6 +1602,15 @@ func (p *pod) parseStructure(parentFieldInfo *fieldInfo, structureValue reflect.
 		} else if err != nil {
 			return err
 		}
+
+		var configEntry configEntry
+
+		if ok, err := configEntry.ParseField(&fieldInfo); ok {
+			p.ConfigEntries = append(p.ConfigEntries, configEntry)
+			continue
+		} else if err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -376,9 +1639,9 @@ func (p *pod) doResolve3(context *resolution3Context, targetEntryPath string) er
 		exportEntry := &p.ExportEntries[i]
 		context.SetActiveEntryPath(exportEntry.Path)
 
-		sort.Slice(exportEntry.FilterEntries, func(i, j int) bool {
-			return exportEntry.FilterEntries[i].Priority >= exportEntry.FilterEntries[j].Priority
-		})
+		if err := exportEntry.SortFilterEntries(); err != nil {
+			return err
+		}
 
 		for _, filterEntry := range exportEntry.FilterEntries {
 			if filterEntry.Pod == p {
@@ -397,6 +1660,7 @@ func (p *pod) doResolve3(context *resolution3Context, targetEntryPath string) er
 }
 
 type fieldInfo struct {
+	PodName        string
 	Parent         *fieldInfo
 	StructureValue reflect.Value
 	StructureType  reflect.Type
@@ -405,18 +1669,130 @@ type fieldInfo struct {
 
 func (fi *fieldInfo) Path() string {
 	if fi.Parent == nil {
+		if fi.PodName != "" {
+			return fi.PodName + "/" + fi.StructureType.String() + "." + fi.Descriptor.Name
+		}
+
 		return fi.StructureType.String() + "." + fi.Descriptor.Name
 	}
 
 	return fi.Parent.Path() + "." + fi.Descriptor.Name
 }
 
+// InlineStructure returns the value of the structure inlined by the field,
+// i.e. the field is tagged with `depinj:"inline"` and is a structure or a
+// pointer to a structure, or the field is embedded and is a structure or a
+// pointer to a structure with entries. The nil pointer of the field inlined is
+// set to a new structure.
+func (fi *fieldInfo) InlineStructure() (reflect.Value, bool, error) {
+	tag, isTagged := fi.Descriptor.Tag.Lookup("depinj")
+
+	if isTagged && tag != "inline" {
+		return reflect.Value{}, false, fmt.Errorf("%w: unknown depinj tag; fieldPath=%q tag=%q",
+			ErrInvalidPod, fi.Path(), tag)
+	}
+
+	if !isTagged && !fi.Descriptor.Anonymous {
+		return reflect.Value{}, false, nil
+	}
+
+	structureType := fi.Descriptor.Type
+	isPointer := structureType.Kind() == reflect.Ptr
+
+	if isPointer {
+		structureType = structureType.Elem()
+	}
+
+	if structureType.Kind() != reflect.Struct {
+		if isTagged {
+			return reflect.Value{}, false, fmt.Errorf("%w: non-structure inline field; fieldPath=%q",
+				ErrInvalidPod, fi.Path())
+		}
+
+		return reflect.Value{}, false, nil
+	}
+
+	if !isTagged && isPointer && !hasEntry(structureType, make(map[reflect.Type]struct{})) {
+		return reflect.Value{}, false, nil
+	}
+
+	if isTagged && !fi.Descriptor.Anonymous && fi.Descriptor.PkgPath != "" {
+		return reflect.Value{}, false, fmt.Errorf("%w: inline field unexported; fieldPath=%q",
+			ErrInvalidPod, fi.Path())
+	}
+
+	for other := fi; other != nil; other = other.Parent {
+		if other.StructureType == structureType {
+			return reflect.Value{}, false, fmt.Errorf("%w: recursive structure; fieldPath=%q",
+				ErrInvalidPod, fi.Path())
+		}
+	}
+
+	fieldValue := fi.StructureValue.Field(fi.Descriptor.Index[0])
+
+	if !isPointer {
+		return fieldValue, true, nil
+	}
+
+	if fieldValue.IsNil() {
+		if !fieldValue.CanSet() {
+			return reflect.Value{}, false, fmt.Errorf("%w: nil pointer field unexported; fieldPath=%q",
+				ErrInvalidPod, fi.Path())
+		}
+
+		fieldValue.Set(reflect.New(structureType))
+	}
+
+	return fieldValue.Elem(), true, nil
+}
+
+// hasEntry returns whether the given structure, or any structure inlined into
+// it, has import/export/filter/config entries.
+func hasEntry(structureType reflect.Type, visitedStructureTypes map[reflect.Type]struct{}) bool {
+	if _, ok := visitedStructureTypes[structureType]; ok {
+		return false
+	}
+
+	visitedStructureTypes[structureType] = struct{}{}
+
+	for i, n := 0, structureType.NumField(); i < n; i++ {
+		field := structureType.Field(i)
+
+		if _, ok := field.Tag.Lookup("depinj"); ok || field.Anonymous {
+			fieldType := field.Type
+
+			if fieldType.Kind() == reflect.Ptr {
+				fieldType = fieldType.Elem()
+			}
+
+			if fieldType.Kind() == reflect.Struct {
+				if hasEntry(fieldType, visitedStructureTypes) {
+					return true
+				}
+
+				continue
+			}
+		}
+
+		for _, fieldTagKey := range [...]string{"import", "export", "filter", "config"} {
+			if _, ok := field.Tag.Lookup(fieldTagKey); ok {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
 type entry struct {
 	// ParseField
 	Path       string
 	FieldValue reflect.Value
 	FieldType  reflect.Type
-	RefID      string
+	RawRefID   string
+
+	// Resolve1
+	RefID string
 }
 
 func (e *entry) ParseField(fieldInfo *fieldInfo, fieldTagKey string) ([]string, bool) {
@@ -430,19 +1806,25 @@ func (e *entry) ParseField(fieldInfo *fieldInfo, fieldTagKey string) ([]string,
 	e.FieldValue = fieldInfo.StructureValue.Field(fieldInfo.Descriptor.Index[0])
 	e.FieldType = fieldInfo.Descriptor.Type
 	args := strings.Split(fieldTagKey, ",")
-	e.RefID = args[0]
+	e.RawRefID = args[0]
 	return args, true
 }
 
-func (e *entry) ResolveRefLink(pod *pod) (string, bool) {
-	if refLink := e.RefID; isRefLink(refLink) {
+func (e *entry) ResolveRefLink(context *resolution12Context, pod *pod) (string, bool) {
+	if refLink := e.RawRefID; tagsyntax.IsRefLink(refLink) {
 		refID, ok := pod.Raw.ResolveRefLink(refLink)
 
 		if !ok {
-			return refLink, false
+			refID, ok = context.ResolveRefLink(refLink)
+
+			if !ok {
+				return refLink, false
+			}
 		}
 
 		e.RefID = refID
+	} else {
+		e.RefID = pod.NamespaceRefID(e.RawRefID)
 	}
 
 	return "", true
@@ -451,6 +1833,9 @@ func (e *entry) ResolveRefLink(pod *pod) (string, bool) {
 type importEntry struct {
 	entry
 
+	// ParseField
+	Selector map[string]string
+
 	// Resolve1
 	Pod *pod
 
@@ -459,7 +1844,7 @@ type importEntry struct {
 }
 
 func (ie *importEntry) ParseField(fieldInfo *fieldInfo) (bool, error) {
-	_, ok := ie.entry.ParseField(fieldInfo, "import")
+	args, ok := ie.entry.ParseField(fieldInfo, "import")
 
 	if !ok {
 		return false, nil
@@ -470,13 +1855,31 @@ func (ie *importEntry) ParseField(fieldInfo *fieldInfo) (bool, error) {
 			ErrBadImportEntry, ie.Path)
 	}
 
+	for _, option := range args[1:] {
+		key, value := tagsyntax.SplitOption(option)
+
+		switch key {
+		case "select":
+			var err error
+			ie.Selector, err = tagsyntax.ParseLabels(value)
+
+			if err != nil {
+				return false, fmt.Errorf("%w: selector parse failed; importEntryPath=%q option=%q: %v",
+					ErrBadImportEntry, ie.Path, option, err)
+			}
+		default:
+			// unknown options are ignored for compatibility, since the
+			// arguments following the ref id used to be ignored.
+		}
+	}
+
 	return true, nil
 }
 
-func (ie *importEntry) Resolve1(pod *pod) error {
+func (ie *importEntry) Resolve1(context *resolution12Context, pod *pod) error {
 	ie.Pod = pod
 
-	if refLink, ok := ie.ResolveRefLink(pod); !ok {
+	if refLink, ok := ie.ResolveRefLink(context, pod); !ok {
 		return fmt.Errorf("%w: unresolvable ref link; importEntryPath=%q refLink=%q",
 			ErrBadImportEntry, ie.Path, refLink)
 	}
@@ -485,9 +1888,35 @@ func (ie *importEntry) Resolve1(pod *pod) error {
 }
 
 func (ie *importEntry) Resolve2(context *resolution12Context) error {
-	if ie.RefID == "" {
+	if len(ie.Selector) >= 1 {
+		var exportEntries []*exportEntry
+
+		for _, exportEntry := range context.ExportEntries(ie.Pod.Module) {
+			if exportEntry.FieldType == ie.FieldType && (ie.RefID == "" || exportEntry.RefID == ie.RefID) &&
+				exportEntry.HasLabels(ie.Selector) {
+				exportEntries = append(exportEntries, exportEntry)
+			}
+		}
+
+		switch len(exportEntries) {
+		case 0:
+			return fmt.Errorf("%w: export entry not found by labels; importEntryPath=%q fieldType=%q selector=%q",
+				ErrBadImportEntry, ie.Path, ie.FieldType, tagsyntax.FormatLabels(ie.Selector))
+		case 1:
+			ie.ExportEntry = exportEntries[0]
+		default:
+			var exportEntryPaths []string
+
+			for _, exportEntry := range exportEntries {
+				exportEntryPaths = append(exportEntryPaths, exportEntry.Path)
+			}
+
+			return fmt.Errorf("%w: ambiguous export entries by labels; importEntryPath=%q fieldType=%q selector=%q exportEntryPaths=%q",
+				ErrBadImportEntry, ie.Path, ie.FieldType, tagsyntax.FormatLabels(ie.Selector), exportEntryPaths)
+		}
+	} else if ie.RefID == "" {
 		var ok bool
-		ie.ExportEntry, ok = context.FindExportEntryByFieldType(ie.FieldType)
+		ie.ExportEntry, ok = context.FindExportEntryByFieldType(ie.Pod.Module, ie.FieldType)
 
 		if !ok {
 			return fmt.Errorf("%w: export entry not found by field type; importEntryPath=%q fieldType=%q",
@@ -495,7 +1924,7 @@ func (ie *importEntry) Resolve2(context *resolution12Context) error {
 		}
 	} else {
 		var ok bool
-		ie.ExportEntry, ok = context.FindExportEntryByRefID(ie.RefID)
+		ie.ExportEntry, ok = context.FindExportEntryByRefID(ie.Pod.Module, ie.RefID)
 
 		if !ok {
 			return fmt.Errorf("%w: export entry not found by ref id; importEntryPath=%q refID=%q",
@@ -508,21 +1937,38 @@ func (ie *importEntry) Resolve2(context *resolution12Context) error {
 		}
 	}
 
+	// ensure idempotence
+	for _, other := range ie.ExportEntry.ImportEntries {
+		if other == ie {
+			return nil
+		}
+	}
+
+	ie.ExportEntry.ImportEntries = append(ie.ExportEntry.ImportEntries, ie)
 	return nil
 }
 
 type exportEntry struct {
 	entry
 
+	// ParseField
+	Labels map[string]string
+
 	// Resolve1
-	Pod *pod
+	Pod   *pod
+	Scope *moduleScope
 
 	// Resolve2
+	ImportEntries []*importEntry
 	FilterEntries []*filterEntry
+
+	// SetUp
+	FilterRecords  []FilterRecord
+	FilterDuration time.Duration
 }
 
 func (ee *exportEntry) ParseField(fieldInfo *fieldInfo) (bool, error) {
-	_, ok := ee.entry.ParseField(fieldInfo, "export")
+	args, ok := ee.entry.ParseField(fieldInfo, "export")
 
 	if !ok {
 		return false, nil
@@ -533,29 +1979,93 @@ func (ee *exportEntry) ParseField(fieldInfo *fieldInfo) (bool, error) {
 			ErrBadExportEntry, ee.Path)
 	}
 
+	for _, option := range args[1:] {
+		key, value := tagsyntax.SplitOption(option)
+
+		switch key {
+		case "labels":
+			var err error
+			ee.Labels, err = tagsyntax.ParseLabels(value)
+
+			if err != nil {
+				return false, fmt.Errorf("%w: labels parse failed; exportEntryPath=%q option=%q: %v",
+					ErrBadExportEntry, ee.Path, option, err)
+			}
+		default:
+			// unknown options are ignored for compatibility, since the
+			// arguments following the ref id used to be ignored.
+		}
+	}
+
 	return true, nil
 }
 
+func (ee *exportEntry) HasLabels(labels map[string]string) bool {
+	return tagsyntax.HasLabels(ee.Labels, labels)
+}
+
 func (ee *exportEntry) Resolve1(context *resolution12Context, pod *pod) error {
 	ee.Pod = pod
 
-	if refLink, ok := ee.ResolveRefLink(pod); !ok {
+	if refLink, ok := ee.ResolveRefLink(context, pod); !ok {
 		return fmt.Errorf("%w: unresolvable ref link; exportEntryPath=%q refLink=%q",
 			ErrBadExportEntry, ee.Path, refLink)
 	}
 
-	if ee.RefID == "" {
-		if conflicting, ok := context.AddExportEntryByFieldType(ee, ee.FieldType); !ok {
-			return fmt.Errorf("%w: duplicate field type; exportEntryPath=%q conflictingExportEntryPath=%q fieldType=%q",
-				ErrBadExportEntry, ee.Path, conflicting.Path, ee.FieldType)
+	// the export entry is added to the scope of the module of the pod, and
+	// to the parent scopes as long as the modules publish it.
+	for scope := pod.Module; ; scope = scope.Parent {
+		if ee.RefID == "" {
+			if conflicting, ok := context.AddExportEntryByFieldType(ee, scope, ee.FieldType); !ok {
+				return fmt.Errorf("%w: duplicate field type; exportEntryPath=%q conflictingExportEntryPath=%q fieldType=%q",
+					ErrBadExportEntry, ee.Path, conflicting.Path, ee.FieldType)
+			}
+		} else {
+			if conflicting, ok := context.AddExportEntryByRefID(ee, scope, ee.RefID); !ok {
+				return fmt.Errorf("%w: duplicate ref id; exportEntryPath=%q conflictingExportEntryPath=%q refID=%q",
+					ErrBadExportEntry, ee.Path, conflicting.Path, ee.RefID)
+			}
 		}
-	} else {
-		if conflicting, ok := context.AddExportEntryByRefID(ee, ee.RefID); !ok {
-			return fmt.Errorf("%w: duplicate ref id; exportEntryPath=%q conflictingExportEntryPath=%q refID=%q",
-				ErrBadExportEntry, ee.Path, conflicting.Path, ee.RefID)
+
+		if scope == nil || !scope.Publishes(ee) {
+			ee.Scope = scope
+			break
+		}
+	}
+
+	context.AddExportEntry(ee)
+	return nil
+}
+
+func (ee *exportEntry) SortFilterEntries() error {
+	filterEntries := ee.FilterEntries
+	filters := make([]tagsyntax.Filter, len(filterEntries))
+
+	for i, filterEntry := range filterEntries {
+		filters[i] = tagsyntax.Filter{
+			Path:      filterEntry.Path,
+			ID:        filterEntry.ID,
+			Priority:  filterEntry.Priority,
+			Seq:       filterEntry.Seq,
+			BeforeIDs: filterEntry.BeforeIDs,
+			AfterIDs:  filterEntry.AfterIDs,
 		}
 	}
 
+	indexes, filterEntryPaths := tagsyntax.SortFilters(filters)
+
+	if indexes == nil {
+		return fmt.Errorf("%w; exportEntryPath=%q filterEntryPaths=%q",
+			ErrFilterCircularConstraint, ee.Path, filterEntryPaths)
+	}
+
+	sortedFilterEntries := make([]*filterEntry, len(indexes))
+
+	for i, index := range indexes {
+		sortedFilterEntries[i] = filterEntries[index]
+	}
+
+	copy(filterEntries, sortedFilterEntries)
 	return nil
 }
 
@@ -563,11 +2073,19 @@ type filterEntry struct {
 	entry
 
 	// ParseField
-	Function func(context.Context) error
-	Priority int
+	Function  func(context.Context, FilterTarget) error
+	Priority  int
+	ID        string
+	BeforeIDs []string
+	AfterIDs  []string
+	Selector  map[string]string
 
 	// Resolve1
 	Pod *pod
+	Seq int
+
+	// Resolve2
+	ExportEntries []*exportEntry
 }
 
 func (fe *filterEntry) ParseField(fieldInfo *fieldInfo) (bool, error) {
@@ -600,12 +2118,13 @@ func (fe *filterEntry) ParseField(fieldInfo *fieldInfo) (bool, error) {
 			ErrBadFilterEntry, fe.Path, methodName)
 	}
 
-	rawFunction := functionValue.Interface()
-	fe.Function, ok = rawFunction.(func(context.Context) error)
+	fe.Function, ok = makeFilterFunction(functionValue, fe.FieldValue)
 
 	if !ok {
-		return false, fmt.Errorf("%w: function type mismatch (expected `%T`, got `%T`); filterEntryPath=%q methodName=%q",
-			ErrBadFilterEntry, fe.Function, rawFunction, fe.Path, methodName)
+		valueType := fe.FieldType.Elem()
+		decoratorFunctionType := reflect.FuncOf([]reflect.Type{contextType, valueType}, []reflect.Type{valueType, errorType}, false)
+		return false, fmt.Errorf("%w: function type mismatch (expected `%T` or `%v`, got `%v`); filterEntryPath=%q methodName=%q",
+			ErrBadFilterEntry, (func(context.Context) error)(nil), decoratorFunctionType, functionValue.Type(), fe.Path, methodName)
 	}
 
 	if len(args) < 3 {
@@ -613,22 +2132,62 @@ func (fe *filterEntry) ParseField(fieldInfo *fieldInfo) (bool, error) {
 			ErrBadFilterEntry, fe.Path)
 	}
 
-	priorityStr := args[2]
-	var err error
-	fe.Priority, err = strconv.Atoi(priorityStr)
+	options := args[2:]
 
-	if err != nil {
-		return false, fmt.Errorf("%w: priority parse failed; filterEntryPath=%q priorityStr=%q: %v",
-			ErrBadFilterEntry, fe.Path, priorityStr, err)
+	// the priority is optional if any option follows, e.g. `before=auth_filter`
+	if priorityStr := options[0]; !strings.Contains(priorityStr, "=") {
+		var err error
+		fe.Priority, err = strconv.Atoi(priorityStr)
+
+		if err != nil {
+			return false, fmt.Errorf("%w: priority parse failed; filterEntryPath=%q priorityStr=%q: %v",
+				ErrBadFilterEntry, fe.Path, priorityStr, err)
+		}
+
+		options = options[1:]
+	}
+
+	for _, option := range options {
+		key, value := tagsyntax.SplitOption(option)
+
+		if (key == "id" || key == "before" || key == "after") && value == "" {
+			return false, fmt.Errorf("%w: empty filter id; filterEntryPath=%q option=%q",
+				ErrBadFilterEntry, fe.Path, option)
+		}
+
+		switch key {
+		case "id":
+			fe.ID = value
+		case "before":
+			fe.BeforeIDs = append(fe.BeforeIDs, value)
+		case "after":
+			fe.AfterIDs = append(fe.AfterIDs, value)
+		case "select":
+			var err error
+			fe.Selector, err = tagsyntax.ParseLabels(value)
+
+			if err != nil {
+				return false, fmt.Errorf("%w: selector parse failed; filterEntryPath=%q option=%q: %v",
+					ErrBadFilterEntry, fe.Path, option, err)
+			}
+		default:
+			// unknown options are ignored for compatibility, since the
+			// arguments following the priority used to be ignored.
+		}
 	}
 
 	return true, nil
 }
 
-func (fe *filterEntry) Resolve1(pod *pod) error {
+func (fe *filterEntry) HasID(id string) bool {
+	return id == fe.Path || (fe.ID != "" && id == fe.ID)
+}
+
+func (fe *filterEntry) Resolve1(context *resolution12Context, pod *pod) error {
 	fe.Pod = pod
+	fe.Seq = context.NextFilterEntrySeq()
 
-	if refLink, ok := fe.ResolveRefLink(pod); !ok {
+	if refLink, ok := fe.ResolveRefLink(context, pod); !ok {
 		return fmt.Errorf("%w: unresolvable ref link; filterEntryPath=%q refLink=%q",
 			ErrBadFilterEntry, fe.Path, refLink)
 	}
@@ -637,12 +2196,33 @@ func (fe *filterEntry) Resolve1(pod *pod) error {
 }
 
 func (fe *filterEntry) Resolve2(context *resolution12Context) error {
+	if fe.IsWildcard() {
+		fe.ExportEntries = fe.ExportEntries[:0]
+		fieldType := fe.FieldType.Elem()
+
+		for _, exportEntry := range context.ExportEntries(fe.Pod.Module) {
+			if exportEntry.FieldType != fieldType || !exportEntry.HasLabels(fe.Selector) {
+				continue
+			}
+
+			if fe.RefID != "" {
+				if !tagsyntax.MatchRefID(fe.RefID, exportEntry.RefID) {
+					continue
+				}
+			}
+
+			fe.attachTo(exportEntry)
+		}
+
+		return nil
+	}
+
 	var exportEntry *exportEntry
 
 	if fe.RefID == "" {
 		fieldType := fe.FieldType.Elem()
 		var ok bool
-		exportEntry, ok = context.FindExportEntryByFieldType(fieldType)
+		exportEntry, ok = context.FindExportEntryByFieldType(fe.Pod.Module, fieldType)
 
 		if !ok {
 			return fmt.Errorf("%w: export entry not found by field type; filterEntryPath=%q fieldType=%q",
@@ -650,7 +2230,7 @@ func (fe *filterEntry) Resolve2(context *resolution12Context) error {
 		}
 	} else {
 		var ok bool
-		exportEntry, ok = context.FindExportEntryByRefID(fe.RefID)
+		exportEntry, ok = context.FindExportEntryByRefID(fe.Pod.Module, fe.RefID)
 
 		if !ok {
 			return fmt.Errorf("%w: export entry not found by ref id; filterEntryPath=%q refID=%q",
@@ -663,54 +2243,168 @@ func (fe *filterEntry) Resolve2(context *resolution12Context) error {
 		}
 	}
 
+	fe.ExportEntries = fe.ExportEntries[:0]
+	fe.attachTo(exportEntry)
+	return nil
+}
+
+func (fe *filterEntry) IsWildcard() bool {
+	return len(fe.Selector) >= 1 || strings.ContainsAny(fe.RefID, "*?[")
+}
+
+func (fe *filterEntry) attachTo(exportEntry *exportEntry) {
+	fe.ExportEntries = append(fe.ExportEntries, exportEntry)
+
 	// ensure idempotence
 	for _, other := range exportEntry.FilterEntries {
 		if other == fe {
-			return nil
+			return
 		}
 	}
 
 	exportEntry.FilterEntries = append(exportEntry.FilterEntries, fe)
-	return nil
 }
 
 type resolution12Context struct {
-	fieldType2ExportEntry map[reflect.Type]*exportEntry
-	refID2ExportEntry     map[string]*exportEntry
+	refLinkResolvers      []RefLinkResolver
+	configSources         []ConfigSource
+	fieldType2ExportEntry map[fieldTypeInScope]*exportEntry
+	refID2ExportEntry     map[refIDInScope]*exportEntry
+	exportEntries         []*exportEntry
+	reachedPods           map[*pod]struct{}
+	podErrs               map[*pod]error
+	filterEntrySeq        int
 }
 
-func (rc *resolution12Context) Init() *resolution12Context {
-	rc.fieldType2ExportEntry = make(map[reflect.Type]*exportEntry)
-	rc.refID2ExportEntry = make(map[string]*exportEntry)
+func (rc *resolution12Context) Init(refLinkResolvers []RefLinkResolver, configSources []ConfigSource) *resolution12Context {
+	rc.refLinkResolvers = refLinkResolvers
+	rc.configSources = configSources
+	rc.fieldType2ExportEntry = make(map[fieldTypeInScope]*exportEntry)
+	rc.refID2ExportEntry = make(map[refIDInScope]*exportEntry)
+	rc.reachedPods = make(map[*pod]struct{})
+	rc.podErrs = make(map[*pod]error)
 	return rc
 }
 
-func (rc *resolution12Context) AddExportEntryByFieldType(exportEntry *exportEntry, fieldType reflect.Type) (*exportEntry, bool) {
-	if addedExportEntry, ok := rc.fieldType2ExportEntry[fieldType]; ok {
+func (rc *resolution12Context) ResolveRefLink(refLink string) (string, bool) {
+	for _, refLinkResolver := range rc.refLinkResolvers {
+		if refID, ok := refLinkResolver.ResolveRefLink(refLink); ok {
+			return refID, true
+		}
+	}
+
+	return "", false
+}
+
+func (rc *resolution12Context) LookupConfig(key string) (interface{}, bool) {
+	for _, configSource := range rc.configSources {
+		if value, ok := configSource.LookupConfig(key); ok {
+			return value, true
+		}
+	}
+
+	return nil, false
+}
+
+func (rc *resolution12Context) NextFilterEntrySeq() int {
+	rc.filterEntrySeq++
+	return rc.filterEntrySeq
+}
+
+func (rc *resolution12Context) ReachPod(pod *pod) bool {
+	if _, ok := rc.reachedPods[pod]; ok {
+		return false
+	}
+
+	rc.reachedPods[pod] = struct{}{}
+	return true
+}
+
+func (rc *resolution12Context) DeferPodError(pod *pod, err error) {
+	if err != nil {
+		rc.podErrs[pod] = err
+	}
+}
+
+func (rc *resolution12Context) PodError(pod *pod) error {
+	return rc.podErrs[pod]
+}
+
+func (rc *resolution12Context) AddExportEntryByFieldType(exportEntry *exportEntry, scope *moduleScope, fieldType reflect.Type) (*exportEntry, bool) {
+	key := fieldTypeInScope{scope, fieldType}
+
+	if addedExportEntry, ok := rc.fieldType2ExportEntry[key]; ok {
 		return addedExportEntry, false
 	}
 
-	rc.fieldType2ExportEntry[fieldType] = exportEntry
+	rc.fieldType2ExportEntry[key] = exportEntry
 	return nil, true
 }
 
-func (rc *resolution12Context) AddExportEntryByRefID(exportEntry *exportEntry, refID string) (*exportEntry, bool) {
-	if addedExportEntry, ok := rc.refID2ExportEntry[refID]; ok {
+func (rc *resolution12Context) AddExportEntryByRefID(exportEntry *exportEntry, scope *moduleScope, refID string) (*exportEntry, bool) {
+	key := refIDInScope{scope, refID}
+
+	if addedExportEntry, ok := rc.refID2ExportEntry[key]; ok {
 		return addedExportEntry, false
 	}
 
-	rc.refID2ExportEntry[refID] = exportEntry
+	rc.refID2ExportEntry[key] = exportEntry
 	return nil, true
 }
 
-func (rc *resolution12Context) FindExportEntryByFieldType(fieldType reflect.Type) (*exportEntry, bool) {
-	exportEntry, ok := rc.fieldType2ExportEntry[fieldType]
-	return exportEntry, ok
+func (rc *resolution12Context) AddExportEntry(exportEntry *exportEntry) {
+	rc.exportEntries = append(rc.exportEntries, exportEntry)
+}
+
+// ExportEntries returns the export entries visible in the given scope.
+func (rc *resolution12Context) ExportEntries(scope *moduleScope) []*exportEntry {
+	var exportEntries []*exportEntry
+
+	for _, exportEntry := range rc.exportEntries {
+		if exportEntry.Scope.Encloses(scope) {
+			exportEntries = append(exportEntries, exportEntry)
+		}
+	}
+
+	return exportEntries
+}
+
+// FindExportEntryByFieldType finds the export entry by the given field type in
+// the given scope, then in the parent scopes.
+func (rc *resolution12Context) FindExportEntryByFieldType(scope *moduleScope, fieldType reflect.Type) (*exportEntry, bool) {
+	for ; ; scope = scope.Parent {
+		if exportEntry, ok := rc.fieldType2ExportEntry[fieldTypeInScope{scope, fieldType}]; ok {
+			return exportEntry, true
+		}
+
+		if scope == nil {
+			return nil, false
+		}
+	}
+}
+
+// FindExportEntryByRefID finds the export entry by the given ref id in the
+// given scope, then in the parent scopes.
+func (rc *resolution12Context) FindExportEntryByRefID(scope *moduleScope, refID string) (*exportEntry, bool) {
+	for ; ; scope = scope.Parent {
+		if exportEntry, ok := rc.refID2ExportEntry[refIDInScope{scope, refID}]; ok {
+			return exportEntry, true
+		}
+
+		if scope == nil {
+			return nil, false
+		}
+	}
+}
+
+type fieldTypeInScope struct {
+	Scope     *moduleScope
+	FieldType reflect.Type
 }
 
-func (rc *resolution12Context) FindExportEntryByRefID(refID string) (*exportEntry, bool) {
-	exportEntry, ok := rc.refID2ExportEntry[refID]
-	return exportEntry, ok
+type refIDInScope struct {
+	Scope *moduleScope
+	RefID string
 }
 
 type resolution3Context struct {
@@ -774,6 +2468,15 @@ func (rc *resolution3Context) DumpStack() string {
 	return stackTraceBuffer.String()
 }
 
+func (rc *resolution3Context) AddResolvedPods(firstPod *pod, lastPod *pod) {
+	for pod := firstPod; pod != nil; pod = pod.Next {
+		rc.podStates[pod] = resolution3PodLeft
+	}
+
+	rc.firstPod = firstPod
+	rc.lastPod = lastPod
+}
+
 func (rc *resolution3Context) AppendPod(pod *pod) {
 	pod.Next = nil // ensure idempotence
 	pod.Prev = rc.lastPod
@@ -802,6 +2505,77 @@ type resolution3StackFrame struct {
 
 type resolution3PodState int
 
-func isRefLink(refLink string) bool {
-	return len(refLink) >= 1 && refLink[0] == '@'
+var (
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+var filterTargetType = reflect.TypeOf(FilterTarget{})
+
+func makeFilterFunction(functionValue reflect.Value, fieldValue reflect.Value) (func(context.Context, FilterTarget) error, bool) {
+	switch rawFunction := functionValue.Interface().(type) {
+	case func(context.Context) error:
+		return func(ctx context.Context, _ FilterTarget) error { return rawFunction(ctx) }, true
+	case func(context.Context, FilterTarget) error:
+		return rawFunction, true
+	}
+
+	valueType := fieldValue.Type().Elem()
+	returnTypes := []reflect.Type{valueType, errorType}
+
+	switch functionValue.Type() {
+	case reflect.FuncOf([]reflect.Type{contextType, valueType}, returnTypes, false):
+		return func(ctx context.Context, _ FilterTarget) error {
+			value := fieldValue.Elem()
+			results := functionValue.Call([]reflect.Value{reflect.ValueOf(&ctx).Elem(), value})
+			return setFilterResults(value, results)
+		}, true
+	case reflect.FuncOf([]reflect.Type{contextType, filterTargetType, valueType}, returnTypes, false):
+		return func(ctx context.Context, target FilterTarget) error {
+			value := fieldValue.Elem()
+			results := functionValue.Call([]reflect.Value{reflect.ValueOf(&ctx).Elem(), reflect.ValueOf(target), value})
+			return setFilterResults(value, results)
+		}, true
+	default:
+		return nil, false
+	}
+}
+
+func setFilterResults(value reflect.Value, results []reflect.Value) error {
+	if err, _ := results[1].Interface().(error); err != nil {
+		return err
+	}
+
+	value.Set(results[0])
+	return nil
+}
+
+func removeImportEntry(importEntries []*importEntry, importEntry *importEntry) []*importEntry {
+	for i, other := range importEntries {
+		if other == importEntry {
+			return append(importEntries[:i], importEntries[i+1:]...)
+		}
+	}
+
+	return importEntries
+}
+
+func removeExportEntry(exportEntries []*exportEntry, exportEntry *exportEntry) []*exportEntry {
+	for i, other := range exportEntries {
+		if other == exportEntry {
+			return append(exportEntries[:i], exportEntries[i+1:]...)
+		}
+	}
+
+	return exportEntries
+}
+
+func removeFilterEntry(filterEntries []*filterEntry, filterEntry *filterEntry) []*filterEntry {
+	for i, other := range filterEntries {
+		if other == filterEntry {
+			return append(filterEntries[:i], filterEntries[i+1:]...)
+		}
+	}
+
+	return filterEntries
 }
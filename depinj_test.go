@@ -3,6 +3,8 @@ package depinj_test
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -194,7 +196,7 @@ func TestErrInvalidPod(t *testing.T) {
 	}{
 		{podA1(0), depinj.ErrInvalidPod, "depinj: invalid pod: non-pointer type; podType=\"depinj_test.podA1\""},
 		{&p, depinj.ErrInvalidPod, "depinj: invalid pod: non-structure pointer type; podType=\"*depinj_test.podA1\""},
-		{&depinj.DummyPod{}, depinj.ErrInvalidPod, "depinj: invalid pod: no import/export/filter entry; podType=\"*depinj.DummyPod\""},
+		{&depinj.DummyPod{}, depinj.ErrInvalidPod, "depinj: invalid pod: no import/export/filter/config entry; podType=\"*depinj.DummyPod\""},
 	} {
 		var pp depinj.PodPool
 		err := pp.AddPod(tt.Pod)
@@ -267,6 +269,20 @@ type podB10 struct {
 	foo int `export:""`
 }
 
+type podB11 struct {
+	depinj.DummyPod
+	Foo *int `filter:",ModifyFoo,0,before="`
+}
+
+func (*podB11) ModifyFoo(context.Context) error { return nil }
+
+type podB12 struct {
+	depinj.DummyPod
+	Foo *int `filter:",ModifyFoo,0,extra,x=y"`
+}
+
+func (*podB12) ModifyFoo(context.Context) error { return nil }
+
 func TestFieldParseFailed(t *testing.T) {
 	for _, tt := range []struct {
 		Pod    depinj.Pod
@@ -277,18 +293,23 @@ func TestFieldParseFailed(t *testing.T) {
 		{&podB2{}, depinj.ErrBadFilterEntry, "depinj: bad filter entry: missing argument `methodName`; filterEntryPath=\"depinj_test.podB2.Foo\""},
 		{&podB3{}, depinj.ErrBadFilterEntry, "depinj: bad filter entry: method undefined or unexported; filterEntryPath=\"depinj_test.podB3.Foo\" methodName=\"ModifyFoo\""},
 		{&podB4{}, depinj.ErrBadFilterEntry, "depinj: bad filter entry: method undefined or unexported; filterEntryPath=\"depinj_test.podB4.Foo\" methodName=\"modifyFoo\""},
-		{&podB5{}, depinj.ErrBadFilterEntry, "depinj: bad filter entry: function type mismatch (expected `func(context.Context) error`, got `func() error`); filterEntryPath=\"depinj_test.podB5.Foo\" methodName=\"ModifyFoo\""},
+		{&podB5{}, depinj.ErrBadFilterEntry, "depinj: bad filter entry: function type mismatch (expected `func(context.Context) error` or `func(context.Context, int) (int, error)`, got `func() error`); filterEntryPath=\"depinj_test.podB5.Foo\" methodName=\"ModifyFoo\""},
 		{&podB6{}, depinj.ErrBadFilterEntry, "depinj: bad filter entry: missing argument `priority`; filterEntryPath=\"depinj_test.podB6.Foo\""},
 		{&podB7{}, depinj.ErrBadFilterEntry, "depinj: bad filter entry: priority parse failed; filterEntryPath=\"depinj_test.podB7.Foo\" priorityStr=\"\": strconv.Atoi: parsing \"\": invalid syntax"},
 		{&podB8{}, depinj.ErrBadFilterEntry, "depinj: bad filter entry: field unexported; filterEntryPath=\"depinj_test.podB8.foo\""},
 		{&podB9{}, depinj.ErrBadImportEntry, "depinj: bad import entry: field unexported; importEntryPath=\"depinj_test.podB9.foo\""},
 		{&podB10{}, depinj.ErrBadExportEntry, "depinj: bad export entry: field unexported; exportEntryPath=\"depinj_test.podB10.foo\""},
+		{&podB11{}, depinj.ErrBadFilterEntry, "depinj: bad filter entry: empty filter id; filterEntryPath=\"depinj_test.podB11.Foo\" option=\"before=\""},
 	} {
 		var pp depinj.PodPool
 		err := pp.AddPod(tt.Pod)
 		assert.True(t, errors.Is(err, tt.Err))
 		assert.EqualError(t, err, tt.ErrMsg)
 	}
+
+	var pp depinj.PodPool
+	err := pp.AddPod(&podB12{})
+	assert.NoError(t, err, "unknown filter options are ignored")
 }
 
 type podC1 struct {
@@ -499,3 +520,1102 @@ func TestEntryResolve3Failed(t *testing.T) {
 		pp.TearDown()
 	}
 }
+
+type podF1 struct {
+	depinj.DummyPod
+	Foo int    `export:"Foo"`
+	Bar string `export:"Bar"`
+}
+
+type podF2 struct {
+	depinj.DummyPod
+	Foo int `import:"Foo"`
+}
+
+type podF3 struct {
+	podF2
+}
+
+func (*podF3) HasSideEffects() bool { return true }
+
+type podF4 struct {
+	depinj.DummyPod
+	Bar *string `filter:"Bar,ModifyBar,0"`
+}
+
+func (*podF4) ModifyBar(context.Context) error { return nil }
+
+type podF5 struct {
+	depinj.DummyPod
+	Baz *string `filter:"Baz*,ModifyBaz,0"`
+}
+
+func (*podF5) ModifyBaz(context.Context) error { return nil }
+
+func TestStrictMode(t *testing.T) {
+	for _, tt := range []struct {
+		Pods        []depinj.Pod
+		StrictMode  depinj.StrictMode
+		Err         error
+		ErrMsg      string
+		WarningMsgs []string
+	}{
+		{[]depinj.Pod{&podF1{}, &podF2{}}, depinj.StrictModeOff, nil, "", nil},
+		{[]depinj.Pod{&podF1{}, &podF2{}}, depinj.StrictModeWarn, nil, "", []string{
+			"depinj: unused export entry: no import/filter entry; exportEntryPath=\"depinj_test.podF1.Bar\"",
+			"depinj: unused pod: no dependent pod; podType=\"*depinj_test.podF2\"",
+		}},
+		{[]depinj.Pod{&podF1{}, &podF3{}}, depinj.StrictModeWarn, nil, "", []string{
+			"depinj: unused export entry: no import/filter entry; exportEntryPath=\"depinj_test.podF1.Bar\"",
+		}},
+		{[]depinj.Pod{&podF1{}, &podF3{}, &podF4{}}, depinj.StrictModeError, nil, "", nil},
+		{[]depinj.Pod{&podF1{}, &podF3{}, &podF4{}, &podF5{}}, depinj.StrictModeWarn, nil, "", []string{
+			"depinj: unused filter entry: no export entry matched; filterEntryPath=\"depinj_test.podF5.Baz\"",
+			"depinj: unused pod: no dependent pod; podType=\"*depinj_test.podF5\"",
+		}},
+		{[]depinj.Pod{&podF1{}, &podF3{}, &podF4{}, &podF5{}}, depinj.StrictModeError, depinj.ErrUnusedFilterEntry, "depinj: unused filter entry: no export entry matched; filterEntryPath=\"depinj_test.podF5.Baz\"", nil},
+		{[]depinj.Pod{&podF1{}, &podF2{}}, depinj.StrictModeError, depinj.ErrUnusedExportEntry, "depinj: unused export entry: no import/filter entry; exportEntryPath=\"depinj_test.podF1.Bar\"", nil},
+	} {
+		var pp depinj.PodPool
+		pp.SetStrictMode(tt.StrictMode)
+		for _, p := range tt.Pods {
+			err := pp.AddPod(p)
+			assert.NoError(t, err)
+		}
+		err := pp.SetUp(context.Background())
+		if tt.Err == nil {
+			assert.NoError(t, err)
+		} else {
+			assert.True(t, errors.Is(err, tt.Err))
+			assert.EqualError(t, err, tt.ErrMsg)
+		}
+		var warningMsgs []string
+		for _, warning := range pp.Warnings() {
+			warningMsgs = append(warningMsgs, warning.Error())
+		}
+		assert.Equal(t, tt.WarningMsgs, warningMsgs)
+		pp.TearDown()
+	}
+}
+
+type podG1 struct {
+	podBase
+	Foo int `export:"Foo"`
+}
+
+type podG2 struct {
+	podBase
+	Foo *int `filter:"Foo,ModifyFoo,0"`
+}
+
+func (*podG2) ModifyFoo(context.Context) error { return nil }
+
+type podG3 struct {
+	podBase
+	Foo int `import:"Foo"`
+}
+
+type podG4 struct {
+	podBase
+	Baz int `import:"Baz"`
+}
+
+func (*podG4) SetUp(context.Context) error { return errors.New("unreachable") }
+
+type podG5 struct {
+	podBase
+	Port int `config:"port"`
+}
+
+type podG6 struct {
+	podBase
+	Qux *int `filter:"Qux,ModifyQux,0"`
+	Baz int  `import:"@Baz"`
+}
+
+func (*podG6) ModifyQux(context.Context) error { return nil }
+
+type podG7 struct {
+	podG1
+	Port int `config:"port"`
+}
+
+func TestRootPods(t *testing.T) {
+	var pp depinj.PodPool
+	var s []*podBase
+	pb := podBase{T: t, Stack: &s}
+	p1, p2, p3 := &podG1{podBase: pb}, &podG2{podBase: pb}, &podG3{podBase: pb}
+	for _, p := range []depinj.Pod{&podG4{podBase: pb}, p2, p1, &podG5{podBase: pb}, &podG6{podBase: pb}} {
+		err := pp.AddPod(p)
+		assert.NoError(t, err)
+	}
+	err := pp.AddRootPod(p3)
+	assert.NoError(t, err)
+	err = pp.SetUp(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []*podBase{&p2.podBase, &p1.podBase, &p3.podBase}, s)
+	pp.TearDown()
+	assert.Len(t, s, 0)
+
+	pp = depinj.PodPool{}
+	pp.MustAddPod(&podG7{podG1: podG1{podBase: pb}})
+	pp.MustAddRootPod(&podG3{podBase: pb})
+	err = pp.SetUp(context.Background())
+	assert.True(t, errors.Is(err, depinj.ErrBadConfigEntry), "%v", err)
+}
+
+type podH1 struct {
+	depinj.DummyPod
+	depinj.Namespace
+	Foo int `export:"Foo"`
+	Bar int `export:"Bar"`
+}
+
+func (p *podH1) SetUp(context.Context) error {
+	p.Foo = len(p.Namespace)
+	return nil
+}
+
+type podH2 struct {
+	depinj.DummyPod
+	Foo1 int `import:"@Foo1"`
+	Foo2 int `import:"@Foo2"`
+	T    *testing.T
+}
+
+func (p *podH2) SetUp(context.Context) error {
+	assert.Equal(p.T, 1, p.Foo1)
+	assert.Equal(p.T, 2, p.Foo2)
+	return nil
+}
+
+func TestRefLinkResolversAndNamespaces(t *testing.T) {
+	var pp depinj.PodPool
+	pp.AddRefLinkResolver(depinj.RefLinkResolverFunc(func(refLink string) (string, bool) {
+		if refLink == "@Foo1" {
+			return "a/Foo", true
+		}
+		return "", false
+	}))
+	pp.AddRefLinkResolver(depinj.RefLinkResolverFunc(func(refLink string) (string, bool) {
+		if refLink == "@Foo2" {
+			return "bb/Foo", true
+		}
+		return "", false
+	}))
+	for _, p := range []depinj.Pod{&podH1{Namespace: "a"}, &podH1{Namespace: "bb"}, &podH2{T: t}} {
+		err := pp.AddPod(p)
+		assert.NoError(t, err)
+	}
+	err := pp.SetUp(context.Background())
+	assert.NoError(t, err)
+	pp.TearDown()
+	err = pp.SetUp(context.Background())
+	assert.NoError(t, err)
+	pp.TearDown()
+}
+
+type podX1 struct {
+	depinj.DummyPod
+	DSN  string `export:"dsn"`
+	Conn string `import:"conn"`
+}
+
+func (p *podX1) SetUp(context.Context) error { p.DSN = "dsn of " + p.Conn; return nil }
+
+type podX2 struct {
+	depinj.DummyPod
+	Conn string `export:"conn"`
+}
+
+type podX3 struct {
+	depinj.DummyPod
+	Primary string `import:"primary/dsn"`
+	Replica string `import:"replica/dsn"`
+	T       *testing.T
+}
+
+func (p *podX3) SetUp(context.Context) error {
+	assert.Equal(p.T, "dsn of primary", p.Primary)
+	assert.Equal(p.T, "dsn of replica", p.Replica)
+	return nil
+}
+
+func TestAddNamedPod(t *testing.T) {
+	var pp depinj.PodPool
+	pp.MustAddNamedPod("primary", &podX1{})
+	pp.MustAddNamedPod("replica", &podX1{})
+	pp.MustAddNamedPod("primary", &podX2{Conn: "primary"})
+	pp.MustAddNamedPod("replica", &podX2{Conn: "replica"})
+	pp.MustAddPod(&podX3{T: t})
+	graph, err := pp.Graph()
+	if assert.NoError(t, err) {
+		var paths []string
+		for _, graphPod := range graph.Pods {
+			for _, exportEntry := range graphPod.ExportEntries {
+				paths = append(paths, graphPod.Name+": "+exportEntry.Path+" "+exportEntry.RefID)
+			}
+		}
+		assert.Equal(t, []string{
+			"primary: primary/depinj_test.podX2.Conn primary/conn",
+			"primary: primary/depinj_test.podX1.DSN primary/dsn",
+			"replica: replica/depinj_test.podX2.Conn replica/conn",
+			"replica: replica/depinj_test.podX1.DSN replica/dsn",
+		}, paths)
+	}
+	pp.MustSetUp(context.Background())
+	pp.MustTearDown()
+
+	pp = depinj.PodPool{}
+	pp.MustAddNamedPod("primary", &podX1{})
+	pp.MustAddNamedPod("replica", &podX2{})
+	err = pp.SetUp(context.Background())
+	assert.True(t, errors.Is(err, depinj.ErrBadImportEntry), "%v", err)
+	assert.Contains(t, err.Error(), `importEntryPath="primary/depinj_test.podX1.Conn" refID="primary/conn"`)
+
+	err = pp.AddNamedPod("", &podX1{})
+	assert.True(t, errors.Is(err, depinj.ErrInvalidPod), "%v", err)
+}
+
+// PodZ1Base is the base of podZ1.
+type PodZ1Base struct {
+	Conn string `export:"conn"`
+}
+
+type podZ1 struct {
+	depinj.DummyPod
+	*PodZ1Base
+}
+
+func (p *podZ1) SetUp(context.Context) error { p.Conn = "conn"; return nil }
+
+type podZ2 struct {
+	depinj.DummyPod
+	Deps struct {
+		Conn string `import:"conn"`
+	} `depinj:"inline"`
+	T *testing.T
+}
+
+func (p *podZ2) SetUp(context.Context) error {
+	assert.Equal(p.T, "wrapped conn", p.Deps.Conn)
+	return nil
+}
+
+type podZ3Base struct {
+	Conn string `import:"conn"`
+}
+
+type podZ3 struct {
+	depinj.DummyPod
+	*podZ3Base
+}
+
+type podZ4 struct {
+	depinj.DummyPod
+	deps struct {
+		Conn string `import:"conn"`
+	} `depinj:"inline"`
+}
+
+type podZ5 struct {
+	depinj.DummyPod
+	Conn string `depinj:"inline"`
+}
+
+type podZ6Filters struct {
+	Conn *string `filter:"conn,Wrap,0"`
+}
+
+func (*podZ6Filters) Wrap(_ context.Context, conn string) (string, error) {
+	return "wrapped " + conn, nil
+}
+
+type podZ6 struct {
+	depinj.DummyPod
+	Filters *podZ6Filters `depinj:"inline"`
+}
+
+type podZ7Helper struct {
+	Buffer []byte
+}
+
+type podZ7 struct {
+	depinj.DummyPod
+	*sync.Mutex
+	*podZ7Helper
+	Conn string `import:"conn"`
+}
+
+func TestInlineStructures(t *testing.T) {
+	var pp depinj.PodPool
+	pp.MustAddPod(&podZ1{})
+	pp.MustAddPod(&podZ2{T: t})
+	pp.MustAddPod(&podZ6{})
+	graph, err := pp.Graph()
+	if assert.NoError(t, err) {
+		var paths []string
+		for _, graphPod := range graph.Pods {
+			for _, importEntry := range graphPod.ImportEntries {
+				paths = append(paths, importEntry.Path)
+			}
+			for _, exportEntry := range graphPod.ExportEntries {
+				paths = append(paths, exportEntry.Path)
+			}
+			for _, filterEntry := range graphPod.FilterEntries {
+				paths = append(paths, filterEntry.Path)
+			}
+		}
+		assert.ElementsMatch(t, []string{
+			"depinj_test.podZ1.PodZ1Base.Conn",
+			"depinj_test.podZ2.Deps.Conn",
+			"depinj_test.podZ6.Filters.Conn",
+		}, paths)
+	}
+	pp.MustSetUp(context.Background())
+	pp.MustTearDown()
+
+	err = pp.AddPod(&podZ3{podZ3Base: &podZ3Base{}})
+	assert.NoError(t, err)
+
+	p7 := &podZ7{}
+	err = pp.AddPod(p7)
+	if assert.NoError(t, err) {
+		assert.Nil(t, p7.Mutex)
+		assert.Nil(t, p7.podZ7Helper)
+	}
+
+	for _, tt := range []struct {
+		Pod    depinj.Pod
+		ErrMsg string
+	}{
+		{&podZ3{}, "depinj: invalid pod: nil pointer field unexported; fieldPath=\"depinj_test.podZ3.podZ3Base\""},
+		{&podZ4{}, "depinj: invalid pod: inline field unexported; fieldPath=\"depinj_test.podZ4.deps\""},
+		{&podZ5{}, "depinj: invalid pod: non-structure inline field; fieldPath=\"depinj_test.podZ5.Conn\""},
+	} {
+		err := pp.AddPod(tt.Pod)
+		assert.True(t, errors.Is(err, depinj.ErrInvalidPod))
+		assert.EqualError(t, err, tt.ErrMsg)
+	}
+}
+
+type podL1 struct {
+	depinj.DummyPod
+	Greeting string `export:"greeting"`
+}
+
+func (p *podL1) SetUp(context.Context) error {
+	p.Greeting = "Hi"
+	return nil
+}
+
+type podL2 struct {
+	depinj.DummyPod
+	Greeting *string `filter:"greeting,Wrap,1"`
+}
+
+func (*podL2) Wrap(_ context.Context, greeting string) (string, error) { return greeting + "!", nil }
+
+type podL3 struct {
+	depinj.DummyPod
+	Greeting *string `filter:"greeting,Skip,0"`
+	Err      error
+}
+
+func (p *podL3) Skip(context.Context) error { return p.Err }
+
+type podL4 struct {
+	depinj.DummyPod
+	Greeting string `import:"greeting"`
+	T        *testing.T
+}
+
+func (p *podL4) SetUp(context.Context) error {
+	assert.Equal(p.T, "Hi!", p.Greeting)
+	return nil
+}
+
+func TestDecoratorFilters(t *testing.T) {
+	var pp depinj.PodPool
+	for _, p := range []depinj.Pod{&podL1{}, &podL2{}, &podL3{Err: fmt.Errorf("no-op: %w", depinj.ErrFilterSkipped)}, &podL4{T: t}} {
+		err := pp.AddPod(p)
+		assert.NoError(t, err)
+	}
+	err := pp.SetUp(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []depinj.FilterRecord{
+		{FilterEntryPath: "depinj_test.podL2.Greeting", ExportEntryPath: "depinj_test.podL1.Greeting"},
+		{FilterEntryPath: "depinj_test.podL3.Greeting", ExportEntryPath: "depinj_test.podL1.Greeting", IsSkipped: true},
+	}, pp.FilterRecords())
+	pp.TearDown()
+
+	pp = depinj.PodPool{}
+	errFoo := errors.New("foo")
+	for _, p := range []depinj.Pod{&podL1{}, &podL3{Err: errFoo}} {
+		err := pp.AddPod(p)
+		assert.NoError(t, err)
+	}
+	err = pp.SetUp(context.Background())
+	var filterError *depinj.FilterError
+	if assert.True(t, errors.As(err, &filterError)) {
+		assert.Equal(t, "depinj_test.podL3.Greeting", filterError.FilterEntryPath)
+		assert.Equal(t, "depinj_test.podL1.Greeting", filterError.ExportEntryPath)
+	}
+	assert.True(t, errors.Is(err, errFoo))
+}
+
+type podM1 struct {
+	depinj.DummyPod
+	Trace string `export:"trace"`
+}
+
+type podM2 struct {
+	depinj.DummyPod
+	Trace *string `filter:"trace,Append,0"`
+	Name  string
+}
+
+func (p *podM2) Append(context.Context) error {
+	*p.Trace += p.Name
+	return nil
+}
+
+type podM3 struct {
+	depinj.DummyPod
+	Trace *string `filter:"trace,Append,id=auth_filter"`
+}
+
+func (p *podM3) Append(context.Context) error {
+	*p.Trace += "auth"
+	return nil
+}
+
+type podM4 struct {
+	depinj.DummyPod
+	Trace *string `filter:"trace,Append,before=auth_filter"`
+}
+
+func (p *podM4) Append(context.Context) error {
+	*p.Trace += "greeting"
+	return nil
+}
+
+type podM5 struct {
+	depinj.DummyPod
+	Trace *string `filter:"trace,Append,-1,after=depinj_test.podM4.Trace,before=depinj_test.podM6.Trace"`
+}
+
+func (p *podM5) Append(context.Context) error { return nil }
+
+type podM6 struct {
+	depinj.DummyPod
+	Trace *string `filter:"trace,Append,-1,before=depinj_test.podM4.Trace"`
+}
+
+func (p *podM6) Append(context.Context) error { return nil }
+
+type podM7 struct {
+	depinj.DummyPod
+	Trace string `import:"trace"`
+	T     *testing.T
+}
+
+func (p *podM7) SetUp(context.Context) error {
+	assert.Equal(p.T, "abgreetingauthc", p.Trace)
+	return nil
+}
+
+func TestFilterOrder(t *testing.T) {
+	var pp depinj.PodPool
+	for _, p := range []depinj.Pod{&podM1{}, &podM3{}, &podM2{Name: "a"}, &podM2{Name: "b"}, &podM4{}, &podM2{Name: "c"}, &podM7{T: t}} {
+		err := pp.AddPod(p)
+		assert.NoError(t, err)
+	}
+	err := pp.SetUp(context.Background())
+	assert.NoError(t, err)
+	pp.TearDown()
+
+	pp = depinj.PodPool{}
+	for _, p := range []depinj.Pod{&podM1{}, &podM4{}, &podM5{}, &podM6{}} {
+		err := pp.AddPod(p)
+		assert.NoError(t, err)
+	}
+	err = pp.SetUp(context.Background())
+	assert.True(t, errors.Is(err, depinj.ErrFilterCircularConstraint))
+	assert.EqualError(t, err, "depinj: filter circular constraint; exportEntryPath=\"depinj_test.podM1.Trace\" filterEntryPaths=[\"depinj_test.podM4.Trace\" \"depinj_test.podM5.Trace\" \"depinj_test.podM6.Trace\"]")
+
+	pp = depinj.PodPool{}
+	pp.SetStrictMode(depinj.StrictModeError)
+	for _, p := range []depinj.Pod{&podM1{}, &podM4{}, &podM7{T: t}} {
+		err := pp.AddPod(p)
+		assert.NoError(t, err)
+	}
+	err = pp.SetUp(context.Background())
+	assert.True(t, errors.Is(err, depinj.ErrBadFilterEntry))
+	assert.EqualError(t, err, "depinj: bad filter entry: unknown filter id; filterEntryPath=\"depinj_test.podM4.Trace\" filterID=\"auth_filter\"")
+}
+
+type podN1 struct {
+	depinj.DummyPod
+	Handler1 string `export:"http_handler_1"`
+	Handler2 string `export:"http_handler_2"`
+	Other    string `export:"other"`
+}
+
+func (p *podN1) SetUp(context.Context) error {
+	p.Handler1, p.Handler2, p.Other = "h1", "h2", "o"
+	return nil
+}
+
+type podN2 struct {
+	depinj.DummyPod
+	Handler *string `filter:"http_handler_*,Trace,0"`
+}
+
+func (*podN2) Trace(_ context.Context, target depinj.FilterTarget, handler string) (string, error) {
+	return handler + "+" + target.RefID + "@" + target.ExportEntryPath, nil
+}
+
+type podN3 struct {
+	depinj.DummyPod
+	Handler1 string `import:"http_handler_1"`
+	Handler2 string `import:"http_handler_2"`
+	Other    string `import:"other"`
+	T        *testing.T
+}
+
+func (p *podN3) SetUp(context.Context) error {
+	assert.Equal(p.T, "h1+http_handler_1@depinj_test.podN1.Handler1", p.Handler1)
+	assert.Equal(p.T, "h2+http_handler_2@depinj_test.podN1.Handler2", p.Handler2)
+	assert.Equal(p.T, "o", p.Other)
+	return nil
+}
+
+type podN4 struct {
+	depinj.DummyPod
+	Handler *string `filter:"**/http_handler_*,Trace,0"`
+}
+
+func (*podN4) Trace(ctx context.Context, target depinj.FilterTarget, handler string) (string, error) {
+	return (*podN2).Trace(nil, ctx, target, handler)
+}
+
+type podN5 struct {
+	depinj.DummyPod
+	Handler1 string `import:"primary/http_handler_1"`
+	T        *testing.T
+}
+
+func (p *podN5) SetUp(context.Context) error {
+	assert.Equal(p.T, "h1+primary/http_handler_1@primary/depinj_test.podN1.Handler1", p.Handler1)
+	return nil
+}
+
+func TestWildcardFilters(t *testing.T) {
+	var pp depinj.PodPool
+	for _, p := range []depinj.Pod{&podN3{T: t}, &podN2{}, &podN1{}} {
+		err := pp.AddPod(p)
+		assert.NoError(t, err)
+	}
+	err := pp.SetUp(context.Background())
+	assert.NoError(t, err)
+	pp.TearDown()
+
+	pp = depinj.PodPool{}
+	pp.MustAddPod(&podN5{T: t})
+	pp.MustAddPod(&podN4{})
+	pp.MustAddNamedPod("primary", &podN1{})
+	err = pp.SetUp(context.Background())
+	assert.NoError(t, err)
+	pp.TearDown()
+}
+
+type podP1 struct {
+	depinj.DummyPod
+	Foo    int `export:""`
+	Events *[]string
+}
+
+func (p *podP1) SetUp(context.Context) error {
+	*p.Events = append(*p.Events, "setup 1")
+	return nil
+}
+
+func (p *podP1) AfterAllSetUp(context.Context) error {
+	*p.Events = append(*p.Events, "after all setup 1")
+	return nil
+}
+
+func (p *podP1) BeforeAnyTearDown(context.Context) {
+	*p.Events = append(*p.Events, "before any teardown 1")
+}
+
+func (p *podP1) TearDown() {
+	*p.Events = append(*p.Events, "teardown 1")
+}
+
+type podP2 struct {
+	depinj.DummyPod
+	Foo    int `import:""`
+	Events *[]string
+	Err    error
+}
+
+func (p *podP2) SetUp(context.Context) error {
+	*p.Events = append(*p.Events, "setup 2")
+	return nil
+}
+
+func (p *podP2) AfterAllSetUp(context.Context) error {
+	*p.Events = append(*p.Events, "after all setup 2")
+	return p.Err
+}
+
+func (p *podP2) TearDown() {
+	*p.Events = append(*p.Events, "teardown 2")
+}
+
+func TestSetUpHooks(t *testing.T) {
+	var pp depinj.PodPool
+	var events []string
+	for _, p := range []depinj.Pod{&podP2{Events: &events}, &podP1{Events: &events}} {
+		err := pp.AddPod(p)
+		assert.NoError(t, err)
+	}
+	err := pp.SetUp(context.Background())
+	assert.NoError(t, err)
+	pp.TearDown()
+	assert.Equal(t, []string{
+		"setup 1", "setup 2",
+		"after all setup 1", "after all setup 2",
+		"before any teardown 1",
+		"teardown 2", "teardown 1",
+	}, events)
+
+	pp = depinj.PodPool{}
+	events = nil
+	errFoo := errors.New("foo")
+	for _, p := range []depinj.Pod{&podP2{Events: &events, Err: errFoo}, &podP1{Events: &events}} {
+		err := pp.AddPod(p)
+		assert.NoError(t, err)
+	}
+	err = pp.SetUp(context.Background())
+	assert.True(t, errors.Is(err, errFoo))
+	assert.Equal(t, []string{
+		"setup 1", "setup 2",
+		"after all setup 1", "after all setup 2",
+		"before any teardown 1",
+		"teardown 2", "teardown 1",
+	}, events)
+}
+
+type podR struct {
+	depinj.DummyPod
+	Name   string
+	Events *[]string
+}
+
+func (p *podR) SetUp(context.Context) error {
+	*p.Events = append(*p.Events, "setup "+p.Name)
+	return nil
+}
+
+func (p *podR) TearDown() {
+	*p.Events = append(*p.Events, "teardown "+p.Name)
+}
+
+type podR1 struct {
+	podR
+	Foo int `export:"Foo"`
+}
+
+type podR2 struct {
+	podR
+	Foo int    `import:"Foo"`
+	Bar string `export:"Bar"`
+}
+
+type podR3 struct {
+	podR
+	Bar string `import:"Bar"`
+}
+
+type podR4 struct {
+	podR
+	Baz float64 `export:"Baz"`
+}
+
+type podR5 struct {
+	podR
+	Foo *int `filter:"Foo,ModifyFoo,0"`
+}
+
+func (p *podR5) ModifyFoo(context.Context) error {
+	*p.Events = append(*p.Events, "filter "+p.Name)
+	return nil
+}
+
+func TestRestart(t *testing.T) {
+	var pp depinj.PodPool
+	var events []string
+	r := podR{Events: &events}
+	p1 := &podR1{podR: r}
+	p1.Name = "1"
+	p2 := &podR2{podR: r}
+	p2.Name = "2"
+	p3 := &podR3{podR: r}
+	p3.Name = "3"
+	p4 := &podR4{podR: r}
+	p4.Name = "4"
+	p5 := &podR5{podR: r}
+	p5.Name = "5"
+	for _, p := range []depinj.Pod{p3, p2, p1, p4, p5} {
+		err := pp.AddPod(p)
+		assert.NoError(t, err)
+	}
+	err := pp.Restart(context.Background(), p1)
+	assert.True(t, errors.Is(err, depinj.ErrPodPoolNotSetUp))
+	err = pp.SetUp(context.Background())
+	assert.NoError(t, err)
+	events = nil
+	err = pp.Restart(context.Background(), p1)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"teardown 3", "teardown 2", "teardown 1", "setup 1", "filter 5", "setup 2", "setup 3"}, events)
+	events = nil
+	err = pp.Restart(context.Background(), p5)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"teardown 3", "teardown 2", "teardown 1", "teardown 5", "setup 5", "setup 1", "filter 5", "setup 2", "setup 3"}, events)
+	err = pp.Restart(context.Background(), &podR4{})
+	assert.True(t, errors.Is(err, depinj.ErrPodNotFound))
+	events = nil
+	pp.TearDown()
+	assert.Equal(t, []string{"teardown 4", "teardown 3", "teardown 2", "teardown 1", "teardown 5"}, events)
+}
+
+type podRH struct {
+	podR
+	SetUpErr         error
+	AfterAllSetUpErr error
+}
+
+func (p *podRH) SetUp(context.Context) error {
+	*p.Events = append(*p.Events, "setup "+p.Name)
+	return p.SetUpErr
+}
+
+func (p *podRH) AfterAllSetUp(context.Context) error {
+	*p.Events = append(*p.Events, "after all setup "+p.Name)
+	return p.AfterAllSetUpErr
+}
+
+func (p *podRH) BeforeAnyTearDown(context.Context) {
+	*p.Events = append(*p.Events, "before any teardown "+p.Name)
+}
+
+type podRH1 struct {
+	podRH
+	Qux int `export:"Qux"`
+}
+
+type podRH2 struct {
+	podRH
+	Qux int `import:"Qux"`
+}
+
+type podRH3 struct {
+	podRH
+	Quux int `export:"Quux"`
+}
+
+func TestRestartFailed(t *testing.T) {
+	errFoo := errors.New("foo")
+	for _, tt := range []struct {
+		SetUpErr         error
+		AfterAllSetUpErr error
+		Events           []string
+	}{
+		{errFoo, nil, []string{
+			"before any teardown 2", "before any teardown 1", "teardown 2", "teardown 1",
+			"setup 1", "setup 2",
+			"before any teardown 3",
+			"teardown 3", "teardown 1",
+		}},
+		{nil, errFoo, []string{
+			"before any teardown 2", "before any teardown 1", "teardown 2", "teardown 1",
+			"setup 1", "setup 2", "after all setup 1", "after all setup 2",
+			"before any teardown 3", "before any teardown 1",
+			"teardown 3", "teardown 2", "teardown 1",
+		}},
+	} {
+		var pp depinj.PodPool
+		var events []string
+		r := podRH{podR: podR{Events: &events}}
+		p1 := &podRH1{podRH: r}
+		p1.Name = "1"
+		p2 := &podRH2{podRH: r}
+		p2.Name = "2"
+		p3 := &podRH3{podRH: r}
+		p3.Name = "3"
+		for _, p := range []depinj.Pod{p1, p2, p3} {
+			pp.MustAddPod(p)
+		}
+		pp.MustSetUp(context.Background())
+		events = nil
+		p2.SetUpErr, p2.AfterAllSetUpErr = tt.SetUpErr, tt.AfterAllSetUpErr
+		err := pp.Restart(context.Background(), p1)
+		assert.True(t, errors.Is(err, errFoo), "%v", err)
+		assert.Equal(t, tt.Events, events)
+		assert.Equal(t, depinj.PodPoolStateTornDown, pp.State())
+		assert.False(t, pp.CheckHealth(context.Background()).IsReady)
+		err = pp.TryTearDown()
+		assert.True(t, errors.Is(err, depinj.ErrPodPoolTornDown), "%v", err)
+	}
+}
+
+type podRC struct {
+	depinj.DummyPod
+	Level int `config:"level"`
+	Bar   int `export:"Bar"`
+}
+
+func TestRestartWithConfig(t *testing.T) {
+	var pp depinj.PodPool
+	configMap := depinj.ConfigMap{"level": 1}
+	pp.AddConfigSource(configMap)
+	p := &podRC{}
+	pp.MustAddPod(p)
+	pp.MustSetUp(context.Background())
+	defer pp.TearDown()
+	assert.Equal(t, 1, p.Level)
+
+	configMap["level"] = 2
+	err := pp.Restart(context.Background(), p)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, p.Level)
+
+	configMap["level"] = "abc"
+	err = pp.Restart(context.Background(), p)
+	assert.True(t, errors.Is(err, depinj.ErrBadConfigEntry), "%v", err)
+	assert.Equal(t, depinj.PodPoolStateSetUp, pp.State())
+	assert.Equal(t, 2, p.Level)
+}
+
+type podS3 struct {
+	podR
+	Foo int     `import:"Foo"`
+	Baz float64 `export:"Baz"`
+}
+
+type podS4 struct {
+	podR
+	Baz float64 `import:"Baz"`
+}
+
+func TestAddPodIncrementally(t *testing.T) {
+	var pp depinj.PodPool
+	var events []string
+	r := podR{Events: &events}
+	p1 := &podR1{podR: r}
+	p1.Name = "1"
+	p2 := &podR2{podR: r}
+	p2.Name = "2"
+	p3 := &podS3{podR: r}
+	p3.Name = "3"
+	p4 := &podS4{podR: r}
+	p4.Name = "4"
+	for _, p := range []depinj.Pod{p2, p1} {
+		err := pp.AddPod(p)
+		assert.NoError(t, err)
+	}
+	err := pp.SetUp(context.Background())
+	assert.NoError(t, err)
+	err = pp.SetUp(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"setup 1", "setup 2"}, events)
+	events = nil
+	for _, p := range []depinj.Pod{p4, p3} {
+		err := pp.AddPod(p)
+		assert.NoError(t, err)
+	}
+	err = pp.SetUp(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"setup 3", "setup 4"}, events)
+	graph, err := pp.Graph()
+	if assert.NoError(t, err) {
+		assert.Len(t, graph.Pods, 4)
+	}
+	events = nil
+	pp.TearDown()
+	assert.Equal(t, []string{"teardown 4", "teardown 3", "teardown 2", "teardown 1"}, events)
+
+	pp = depinj.PodPool{}
+	events = nil
+	p5 := &podR5{podR: r}
+	p5.Name = "5"
+	err = pp.AddPod(p1)
+	assert.NoError(t, err)
+	err = pp.SetUp(context.Background())
+	assert.NoError(t, err)
+	err = pp.AddPod(p5)
+	assert.NoError(t, err)
+	err = pp.SetUp(context.Background())
+	assert.True(t, errors.Is(err, depinj.ErrBadFilterEntry))
+	assert.EqualError(t, err, "depinj: bad filter entry: export entry already set up; filterEntryPath=\"depinj_test.podR5.Foo\" exportEntryPath=\"depinj_test.podR1.Foo\"")
+	err = pp.SetUp(context.Background())
+	assert.NoError(t, err)
+	err = pp.RemovePod(p5)
+	assert.True(t, errors.Is(err, depinj.ErrPodNotFound), "%v", err)
+	events = nil
+	pp.TearDown()
+	assert.Equal(t, []string{"teardown 1"}, events)
+
+	pp = depinj.PodPool{}
+	events = nil
+	errFoo := errors.New("foo")
+	p6 := &podP2{Events: &events, Err: errFoo}
+	pp.MustAddPod(&podP1{Events: &events})
+	pp.MustSetUp(context.Background())
+	pp.MustAddPod(p6)
+	err = pp.SetUp(context.Background())
+	assert.True(t, errors.Is(err, errFoo), "%v", err)
+	err = pp.SetUp(context.Background())
+	assert.NoError(t, err)
+	err = pp.RemovePod(p6)
+	assert.True(t, errors.Is(err, depinj.ErrPodNotFound), "%v", err)
+}
+
+func TestRemovePod(t *testing.T) {
+	var pp depinj.PodPool
+	var events []string
+	r := podR{Events: &events}
+	p1 := &podR1{podR: r}
+	p1.Name = "1"
+	p2 := &podR2{podR: r}
+	p2.Name = "2"
+	p3 := &podR3{podR: r}
+	p3.Name = "3"
+	p4 := &podR4{podR: r}
+	p4.Name = "4"
+	for _, p := range []depinj.Pod{p3, p2, p1, p4} {
+		err := pp.AddPod(p)
+		assert.NoError(t, err)
+	}
+	err := pp.SetUp(context.Background())
+	assert.NoError(t, err)
+	err = pp.RemovePod(p1)
+	assert.True(t, errors.Is(err, depinj.ErrPodInUse))
+	assert.EqualError(t, err, "depinj: pod in use; podType=\"*depinj_test.podR1\" importEntryPaths=[\"depinj_test.podR2.Foo\"]")
+	err = pp.RemovePod(&podR4{})
+	assert.True(t, errors.Is(err, depinj.ErrPodNotFound))
+	events = nil
+	err = pp.RemovePod(p4)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"teardown 4"}, events)
+	events = nil
+	err = pp.RemovePodCascade(p1)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"teardown 3", "teardown 2", "teardown 1"}, events)
+	graph, err := pp.Graph()
+	if assert.NoError(t, err) {
+		assert.Len(t, graph.Pods, 0)
+	}
+	events = nil
+	err = pp.AddPod(p1)
+	assert.NoError(t, err)
+	err = pp.SetUp(context.Background())
+	assert.NoError(t, err)
+	pp.TearDown()
+	assert.Equal(t, []string{"setup 1", "teardown 1"}, events)
+}
+
+type podT1 struct {
+	depinj.DummyPod
+	depinj.Namespace
+	OnSetUp func() error
+	Foo     int `export:"Foo"`
+}
+
+func (p *podT1) SetUp(context.Context) error {
+	return p.OnSetUp()
+}
+
+func TestPodPoolState(t *testing.T) {
+	var pp depinj.PodPool
+	assert.Equal(t, depinj.PodPoolStateNew, pp.State())
+	err := pp.TryTearDown()
+	assert.True(t, errors.Is(err, depinj.ErrPodPoolNotSetUp))
+	var errs []error
+	p1 := &podT1{}
+	p1.OnSetUp = func() error {
+		errs = append(errs, pp.AddPod(&podT1{Namespace: "x"}))
+		errs = append(errs, pp.SetUp(context.Background()))
+		errs = append(errs, pp.TryTearDown())
+		_, err := pp.Graph()
+		errs = append(errs, err)
+		assert.Nil(t, pp.FilterRecords())
+		assert.False(t, pp.CheckHealth(context.Background()).IsReady)
+		assert.Equal(t, depinj.PodPoolStateNew, pp.State())
+		return nil
+	}
+	err = pp.AddPod(p1)
+	assert.NoError(t, err)
+	_, err = pp.Graph()
+	assert.NoError(t, err)
+	assert.Equal(t, depinj.PodPoolStateResolved, pp.State())
+	pp.SetStrictMode(depinj.StrictModeOff)
+	assert.Equal(t, depinj.PodPoolStateNew, pp.State())
+	err = pp.SetUp(context.Background())
+	assert.NoError(t, err)
+	if assert.Len(t, errs, 4) {
+		for _, err := range errs {
+			assert.True(t, errors.Is(err, depinj.ErrPodPoolBusy))
+		}
+	}
+	assert.Equal(t, depinj.PodPoolStateSetUp, pp.State())
+	assert.True(t, pp.CheckHealth(context.Background()).IsReady)
+	err = pp.TryTearDown()
+	assert.NoError(t, err)
+	assert.Equal(t, depinj.PodPoolStateTornDown, pp.State())
+	err = pp.TryTearDown()
+	assert.True(t, errors.Is(err, depinj.ErrPodPoolTornDown))
+	err = pp.Restart(context.Background(), p1)
+	assert.True(t, errors.Is(err, depinj.ErrPodPoolNotSetUp))
+	p1.OnSetUp = func() error { return nil }
+	err = pp.SetUp(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, depinj.PodPoolStateSetUp, pp.State())
+	pp.MustTearDown()
+}
+
+func TestPodPoolConcurrency(t *testing.T) {
+	var pp depinj.PodPool
+	var waitGroup sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		waitGroup.Add(1)
+		go func(namespace depinj.Namespace) {
+			defer waitGroup.Done()
+			for {
+				err := pp.AddPod(&podT1{Namespace: namespace, OnSetUp: func() error { return nil }})
+				if err == nil {
+					break
+				}
+				assert.True(t, errors.Is(err, depinj.ErrPodPoolBusy))
+			}
+			err := pp.SetUp(context.Background())
+			if err != nil {
+				assert.True(t, errors.Is(err, depinj.ErrPodPoolBusy))
+			}
+			pp.CheckHealth(context.Background())
+			pp.Warnings()
+		}(depinj.Namespace(fmt.Sprint(i)))
+	}
+	waitGroup.Wait()
+	err := pp.SetUp(context.Background())
+	assert.NoError(t, err)
+	graph, err := pp.Graph()
+	if assert.NoError(t, err) {
+		assert.Len(t, graph.Pods, 10)
+	}
+	err = pp.TryTearDown()
+	assert.NoError(t, err)
+}
@@ -0,0 +1,313 @@
+package depinj
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// PodNamer is an optional interface of Pod.
+type PodNamer interface {
+	// PodName returns the name of the pod, which tells the pod apart from the
+	// other pods of the same type in errors.
+	PodName() (podName string)
+}
+
+// Redactor determines whether the field with the given path, e.g.
+// `app.DB.Password`, is redacted in the dumps of the pods, see
+// PodPool.SetDebugMode.
+type Redactor func(fieldPath string) (redacted bool)
+
+// DefaultRedactor is the default Redactor, which redacts the fields whose
+// names contain, case-insensitively, the whole words `password`, `passwd`,
+// `secret`, `token`, `credential`, `apikey`, `privatekey` or `dsn`, or their
+// plurals. The words of a field name are split by case changes, digits and
+// underscores, e.g. `AccessToken`, `API_KEY` and `DSN` are redacted, whereas
+// `Tokenizer` isn't.
+func DefaultRedactor(fieldPath string) bool {
+	words := splitWords(fieldPath[strings.LastIndexByte(fieldPath, '.')+1:])
+
+	for i := range words {
+		phrase := ""
+
+		for _, word := range words[i:] {
+			phrase += strings.ToLower(word)
+
+			switch strings.TrimSuffix(phrase, "s") {
+			case "password", "passwd", "secret", "token", "credential", "apikey", "privatekey", "dsn":
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// splitWords splits the given identifier into words, e.g. `APIKey2_expiry`
+// into `API`, `Key`, `2` and `expiry`.
+func splitWords(identifier string) []string {
+	var words []string
+	runes := []rune(identifier)
+	start := 0
+
+	for i, r := range runes {
+		if r == '_' {
+			if i > start {
+				words = append(words, string(runes[start:i]))
+			}
+
+			start = i + 1
+			continue
+		}
+
+		if i == start {
+			continue
+		}
+
+		prevRune := runes[i-1]
+
+		if unicode.IsDigit(r) != unicode.IsDigit(prevRune) ||
+			(unicode.IsUpper(r) && unicode.IsLower(prevRune)) ||
+			(unicode.IsUpper(r) && unicode.IsUpper(prevRune) && i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+			words = append(words, string(runes[start:i]))
+			start = i
+		}
+	}
+
+	if len(runes) > start {
+		words = append(words, string(runes[start:]))
+	}
+
+	return words
+}
+
+// DescribePod returns the description of the given pod in errors, which
+// consists of the type of the pod and, if the pod implements PodNamer, the
+// name of the pod, e.g. `podType="*app.DB" podName="primary"`.
+func DescribePod(rawPod Pod) string {
+	return podDescriber{}.Describe(rawPod, "")
+}
+
+// SetDebugMode sets whether the descriptions of the pods in errors include the
+// dumps of the pods, e.g. `podDump="&app.DB{Name:\"primary\", DSN:<redacted>}"`.
+// The fields are redacted by the redactor set by SetRedactor, or
+// DefaultRedactor if it's not set. The dumps are off by default, since they
+// make errors huge and may leak secrets.
+func (pp *PodPool) SetDebugMode(debugMode bool) {
+	pp.mutex.Lock()
+	defer pp.mutex.Unlock()
+	pp.debugMode = debugMode
+}
+
+// SetRedactor sets the redactor of the dumps of the pods in debug mode, see
+// SetDebugMode.
+func (pp *PodPool) SetRedactor(redactor Redactor) {
+	pp.mutex.Lock()
+	defer pp.mutex.Unlock()
+	pp.redactor = redactor
+}
+
+func (pp *PodPool) podDescriber() podDescriber {
+	pp.mutex.Lock()
+	defer pp.mutex.Unlock()
+
+	if !pp.debugMode {
+		return podDescriber{}
+	}
+
+	redactor := pp.redactor
+
+	if redactor == nil {
+		redactor = DefaultRedactor
+	}
+
+	return podDescriber{Redactor: redactor}
+}
+
+// podDescriber describes pods in errors, with the dumps of the pods if the
+// redactor isn't nil, i.e. in debug mode.
+type podDescriber struct {
+	Redactor Redactor
+}
+
+// Describe describes the given pod, which is named by the given name, if not
+// empty, or PodNamer.PodName.
+func (pd podDescriber) Describe(rawPod Pod, podName string) string {
+	var buffer bytes.Buffer
+	fmt.Fprintf(&buffer, "podType=%q", reflect.TypeOf(rawPod))
+
+	if podName == "" {
+		if podNamer, ok := rawPod.(PodNamer); ok {
+			podName = podNamer.PodName()
+		}
+	}
+
+	if podName != "" {
+		fmt.Fprintf(&buffer, " podName=%q", podName)
+	}
+
+	if pd.Redactor != nil {
+		fmt.Fprintf(&buffer, " podDump=%q", pd.dump(reflect.ValueOf(rawPod)))
+	}
+
+	return buffer.String()
+}
+
+func (pd podDescriber) dump(value reflect.Value) string {
+	var buffer bytes.Buffer
+	path := value.Type()
+
+	for path.Kind() == reflect.Ptr {
+		path = path.Elem()
+	}
+
+	pd.dumpValue(&buffer, path.String(), value, make(map[visitedValue]struct{}))
+	return buffer.String()
+}
+
+// visitedValue identifies a pointer, map or slice being dumped, to break
+// reference cycles.
+type visitedValue struct {
+	Pointer uintptr
+	Type    reflect.Type
+}
+
+// dumpValue dumps the given value in the form of `%#v`, except that the
+// fields of structures reached through pointers, interfaces, slices, arrays
+// and maps are redacted by their paths, in which the elements of slices,
+// arrays and maps are not counted.
+func (pd podDescriber) dumpValue(buffer *bytes.Buffer, path string, value reflect.Value, visitedValues map[visitedValue]struct{}) {
+	switch value.Kind() {
+	case reflect.Struct:
+		pd.dumpStructure(buffer, path, value, visitedValues)
+		return
+	case reflect.Interface:
+		if !value.IsNil() {
+			pd.dumpValue(buffer, path, value.Elem(), visitedValues)
+			return
+		}
+	case reflect.Ptr, reflect.Slice, reflect.Map:
+		if value.IsNil() || (value.Kind() == reflect.Ptr && !isComposite(value.Elem().Kind())) {
+			break
+		}
+
+		visitedValue := visitedValue{value.Pointer(), value.Type()}
+
+		if _, ok := visitedValues[visitedValue]; ok {
+			fmt.Fprintf(buffer, "(%v)(%#x)", value.Type(), value.Pointer())
+			return
+		}
+
+		visitedValues[visitedValue] = struct{}{}
+		defer delete(visitedValues, visitedValue)
+
+		switch value.Kind() {
+		case reflect.Ptr:
+			buffer.WriteByte('&')
+			pd.dumpValue(buffer, path, value.Elem(), visitedValues)
+		case reflect.Slice:
+			pd.dumpElements(buffer, path, value, visitedValues)
+		case reflect.Map:
+			pd.dumpMap(buffer, path, value, visitedValues)
+		}
+
+		return
+	case reflect.Array:
+		pd.dumpElements(buffer, path, value, visitedValues)
+		return
+	}
+
+	fmt.Fprintf(buffer, "%#v", value)
+}
+
+func (pd podDescriber) dumpStructure(buffer *bytes.Buffer, path string, structureValue reflect.Value, visitedValues map[visitedValue]struct{}) {
+	structureType := structureValue.Type()
+	buffer.WriteString(structureType.String())
+	buffer.WriteByte('{')
+
+	for i, n := 0, structureType.NumField(); i < n; i++ {
+		if i >= 1 {
+			buffer.WriteString(", ")
+		}
+
+		field := structureType.Field(i)
+		fieldPath := path + "." + field.Name
+		buffer.WriteString(field.Name)
+		buffer.WriteByte(':')
+
+		if pd.Redactor(fieldPath) {
+			buffer.WriteString("<redacted>")
+			continue
+		}
+
+		pd.dumpValue(buffer, fieldPath, structureValue.Field(i), visitedValues)
+	}
+
+	buffer.WriteByte('}')
+}
+
+func (pd podDescriber) dumpElements(buffer *bytes.Buffer, path string, value reflect.Value, visitedValues map[visitedValue]struct{}) {
+	buffer.WriteString(value.Type().String())
+	buffer.WriteByte('{')
+
+	for i, n := 0, value.Len(); i < n; i++ {
+		if i >= 1 {
+			buffer.WriteString(", ")
+		}
+
+		pd.dumpValue(buffer, path, value.Index(i), visitedValues)
+	}
+
+	buffer.WriteByte('}')
+}
+
+func (pd podDescriber) dumpMap(buffer *bytes.Buffer, path string, value reflect.Value, visitedValues map[visitedValue]struct{}) {
+	keys := value.MapKeys()
+	keyDumps := make([]string, len(keys))
+
+	for i, key := range keys {
+		keyDumps[i] = fmt.Sprintf("%#v", key)
+	}
+
+	sort.Sort(mapKeys{keys, keyDumps})
+	buffer.WriteString(value.Type().String())
+	buffer.WriteByte('{')
+
+	for i, key := range keys {
+		if i >= 1 {
+			buffer.WriteString(", ")
+		}
+
+		buffer.WriteString(keyDumps[i])
+		buffer.WriteByte(':')
+		pd.dumpValue(buffer, path, value.MapIndex(key), visitedValues)
+	}
+
+	buffer.WriteByte('}')
+}
+
+func isComposite(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Struct, reflect.Array, reflect.Slice, reflect.Map:
+		return true
+	default:
+		return false
+	}
+}
+
+// mapKeys sorts the keys of a map by their dumps.
+type mapKeys struct {
+	Keys  []reflect.Value
+	Dumps []string
+}
+
+func (mk mapKeys) Len() int           { return len(mk.Keys) }
+func (mk mapKeys) Less(i, j int) bool { return mk.Dumps[i] < mk.Dumps[j] }
+
+func (mk mapKeys) Swap(i, j int) {
+	mk.Keys[i], mk.Keys[j] = mk.Keys[j], mk.Keys[i]
+	mk.Dumps[i], mk.Dumps[j] = mk.Dumps[j], mk.Dumps[i]
+}
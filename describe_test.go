@@ -0,0 +1,142 @@
+package depinj_test
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/depinj"
+)
+
+type podW1 struct {
+	depinj.DummyPod
+	Foo      string `export:"foo"`
+	Password string
+	Options  podW1Options
+	Err      error
+}
+
+type podW1Options struct {
+	Port  int
+	Token string
+}
+
+func (p *podW1) PodName() string { return "primary" }
+
+func (p *podW1) SetUp(context.Context) error { return p.Err }
+
+type podW2 struct {
+	depinj.DummyPod
+	Foo string `import:"foo"`
+}
+
+func (p *podW2) AfterAllSetUp(context.Context) error { return errors.New("too late") }
+
+func TestDescribePod(t *testing.T) {
+	assert.Equal(t, `podType="*depinj_test.podW1" podName="primary"`, depinj.DescribePod(&podW1{}))
+	assert.Equal(t, `podType="*depinj_test.podW2"`, depinj.DescribePod(&podW2{}))
+}
+
+func TestPodSetUpErrors(t *testing.T) {
+	errSetUp := errors.New("connection refused")
+	newPods := func() []depinj.Pod {
+		return []depinj.Pod{&podW1{Foo: "foo", Password: "123456", Options: podW1Options{Port: 80, Token: "abc"}, Err: errSetUp}}
+	}
+
+	var pp depinj.PodPool
+	for _, p := range newPods() {
+		pp.MustAddPod(p)
+	}
+	err := pp.SetUp(context.Background())
+	assert.True(t, errors.Is(err, errSetUp))
+	assert.EqualError(t, err, `depinj: pod setup failed; podType="*depinj_test.podW1" podName="primary": connection refused`)
+
+	pp = depinj.PodPool{}
+	pp.SetDebugMode(true)
+	for _, p := range newPods() {
+		pp.MustAddPod(p)
+	}
+	err = pp.SetUp(context.Background())
+	assert.True(t, errors.Is(err, errSetUp))
+	assert.Regexp(t, "^"+regexp.QuoteMeta(`depinj: pod setup failed; podType="*depinj_test.podW1" podName="primary"`+
+		` podDump="&depinj_test.podW1{DummyPod:depinj.DummyPod{}, Foo:\"foo\", Password:<redacted>,`+
+		` Options:depinj_test.podW1Options{Port:80, Token:<redacted>}, Err:&errors.errorString{s:\"connection refused\"}}"`+
+		`: connection refused`)+"$", err.Error())
+	assert.NotContains(t, err.Error(), "123456")
+	assert.NotContains(t, err.Error(), "abc")
+
+	pp = depinj.PodPool{}
+	pp.SetDebugMode(true)
+	pp.SetRedactor(func(fieldPath string) bool { return fieldPath == "depinj_test.podW1.Foo" })
+	for _, p := range newPods() {
+		pp.MustAddPod(p)
+	}
+	err = pp.SetUp(context.Background())
+	assert.Contains(t, err.Error(), `Foo:<redacted>, Password:\"123456\",`)
+	assert.Contains(t, err.Error(), `Token:\"abc\"`)
+
+	pp = depinj.PodPool{}
+	pp.MustAddNamedPod("secondary", &podW1{Err: errSetUp})
+	err = pp.SetUp(context.Background())
+	assert.EqualError(t, err, `depinj: pod setup failed; podType="*depinj_test.podW1" podName="secondary": connection refused`)
+
+	pp = depinj.PodPool{}
+	pp.MustAddPod(&podW1{})
+	pp.MustAddPod(&podW2{})
+	err = pp.SetUp(context.Background())
+	assert.EqualError(t, err, `depinj: pod after-all-setup hook failed; podType="*depinj_test.podW2": too late`)
+}
+
+func TestDefaultRedactor(t *testing.T) {
+	for _, fieldPath := range []string{"app.DB.Password", "app.DB.DSN", "app.DB.DSNs", "app.Client.APIKey", "app.Client.Options.AccessToken",
+		"app.Client.clientSecret", "app.Client.API_KEY", "app.Client.private_key2", "app.Client.Credentials", "app.Client.token"} {
+		assert.True(t, depinj.DefaultRedactor(fieldPath), fieldPath)
+	}
+
+	for _, fieldPath := range []string{"app.DB.Name", "app.DB.Timeout", "app.Parser.Tokenizer", "app.Parser.TokenizerOptions",
+		"app.DB.Secretary", "app.Client.KeyAPI", strings.Repeat("x", 3)} {
+		assert.False(t, depinj.DefaultRedactor(fieldPath), fieldPath)
+	}
+}
+
+type podW3 struct {
+	depinj.DummyPod
+	Options   *podW1Options
+	Backends  []podW1Options
+	Clients   map[string]*podW1Options
+	Extra     interface{}
+	Tokenizer string `export:"tokenizer"`
+	Self      *podW3
+}
+
+func (p *podW3) SetUp(context.Context) error { return errors.New("oops") }
+
+func TestRedactNestedFields(t *testing.T) {
+	var pp depinj.PodPool
+	pp.SetDebugMode(true)
+	p := &podW3{
+		Options:   &podW1Options{Port: 80, Token: "abc"},
+		Backends:  []podW1Options{{Port: 81, Token: "def"}},
+		Clients:   map[string]*podW1Options{"b": {Port: 83, Token: "jkl"}, "a": {Port: 82, Token: "ghi"}},
+		Extra:     podW1Options{Port: 84, Token: "mno"},
+		Tokenizer: "words",
+	}
+	p.Self = p
+	pp.MustAddPod(p)
+	err := pp.SetUp(context.Background())
+	assert.Regexp(t, regexp.QuoteMeta(`podDump="&depinj_test.podW3{DummyPod:depinj.DummyPod{},`+
+		` Options:&depinj_test.podW1Options{Port:80, Token:<redacted>},`+
+		` Backends:[]depinj_test.podW1Options{depinj_test.podW1Options{Port:81, Token:<redacted>}},`+
+		` Clients:map[string]*depinj_test.podW1Options{\"a\":&depinj_test.podW1Options{Port:82, Token:<redacted>},`+
+		` \"b\":&depinj_test.podW1Options{Port:83, Token:<redacted>}},`+
+		` Extra:depinj_test.podW1Options{Port:84, Token:<redacted>},`+
+		` Tokenizer:\"words\", Self:(*depinj_test.podW3)(`)+`0x[0-9a-f]+\)\}"`, err.Error())
+
+	for _, secret := range []string{"abc", "def", "ghi", "jkl", "mno"} {
+		assert.NotContains(t, err.Error(), secret)
+	}
+}
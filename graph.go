@@ -0,0 +1,344 @@
+package depinj
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/roy2220/depinj/internal/tagsyntax"
+)
+
+// Graph represents the dependency graph of the pods in a pool.
+type Graph struct {
+	// Pods are the pods in the order of setups.
+	Pods []GraphPod `json:"pods"`
+
+	// Edges are the dependencies between the pods.
+	Edges []GraphEdge `json:"edges"`
+}
+
+// GraphPod represents a pod in a graph.
+type GraphPod struct {
+	Type          string             `json:"type"`
+	Name          string             `json:"name,omitempty"`
+	ImportEntries []GraphImportEntry `json:"importEntries,omitempty"`
+	ExportEntries []GraphExportEntry `json:"exportEntries,omitempty"`
+	FilterEntries []GraphFilterEntry `json:"filterEntries,omitempty"`
+}
+
+// GraphImportEntry represents an import entry in a graph.
+type GraphImportEntry struct {
+	Path            string            `json:"path"`
+	FieldType       string            `json:"fieldType"`
+	RefID           string            `json:"refID,omitempty"`
+	Selector        map[string]string `json:"selector,omitempty"`
+	ExportEntryPath string            `json:"exportEntryPath"`
+}
+
+// GraphExportEntry represents an export entry in a graph.
+type GraphExportEntry struct {
+	Path             string            `json:"path"`
+	FieldType        string            `json:"fieldType"`
+	RefID            string            `json:"refID,omitempty"`
+	Labels           map[string]string `json:"labels,omitempty"`
+	ImportEntryPaths []string          `json:"importEntryPaths,omitempty"`
+	FilterEntryPaths []string          `json:"filterEntryPaths,omitempty"`
+}
+
+// GraphFilterEntry represents a filter entry in a graph.
+type GraphFilterEntry struct {
+	Path             string            `json:"path"`
+	FieldType        string            `json:"fieldType"`
+	RefID            string            `json:"refID,omitempty"`
+	Selector         map[string]string `json:"selector,omitempty"`
+	Priority         int               `json:"priority"`
+	ExportEntryPaths []string          `json:"exportEntryPaths,omitempty"`
+}
+
+// GraphEdge represents a dependency between two pods in a graph, the pod
+// `To` depends on the pod `From`, so the pod `From` is set up earlier.
+type GraphEdge struct {
+	Kind GraphEdgeKind `json:"kind"`
+
+	// From is the index of the depended pod in Graph.Pods.
+	From int `json:"from"`
+
+	// FromEntryPath is the path of the export entry imported for GraphEdgeImport,
+	// or the path of the filter entry for GraphEdgeFilter.
+	FromEntryPath string `json:"fromEntryPath"`
+
+	// To is the index of the depending pod in Graph.Pods.
+	To int `json:"to"`
+
+	// ToEntryPath is the path of the import entry for GraphEdgeImport, or the
+	// path of the export entry filtered for GraphEdgeFilter.
+	ToEntryPath string `json:"toEntryPath"`
+}
+
+// GraphEdgeKind represents the kind of a graph edge.
+type GraphEdgeKind string
+
+const (
+	// GraphEdgeImport is the kind of the edge from an export entry to an
+	// import entry.
+	GraphEdgeImport GraphEdgeKind = "import"
+
+	// GraphEdgeFilter is the kind of the edge from a filter entry to an
+	// export entry.
+	GraphEdgeFilter GraphEdgeKind = "filter"
+)
+
+// FindExportEntriesByLabels returns the export entries in the graph with all
+// the given labels.
+func (g *Graph) FindExportEntriesByLabels(labels map[string]string) []GraphExportEntry {
+	var exportEntries []GraphExportEntry
+
+	for i := range g.Pods {
+		for _, exportEntry := range g.Pods[i].ExportEntries {
+			if tagsyntax.HasLabels(exportEntry.Labels, labels) {
+				exportEntries = append(exportEntries, exportEntry)
+			}
+		}
+	}
+
+	return exportEntries
+}
+
+// Graph returns the dependency graph of the pods in the pool. If the pool
+// hasn't been set up, the pods are resolved without being set up, and the pool
+// turns into PodPoolStateResolved, unless it has been torn down, in which case
+// it stays in PodPoolStateTornDown.
+func (pp *PodPool) Graph() (*Graph, error) {
+	state, err := pp.beginOperation()
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { pp.endOperation(state) }()
+
+	if state, err = pp.resolveForGraph(state); err != nil {
+		return nil, err
+	}
+
+	return makeGraph(pp.firstPod), nil
+}
+
+// Explain returns the shortest chain of dependencies which forces the pod `a`
+// to be set up before the pod `b`, i.e. the edges in the dependency graph from
+// the pod `a` to the pod `b`, see Graph.Explain. It returns nil if there is
+// no such chain. As Graph does, the pods are resolved without being set up if
+// the pool hasn't been set up.
+func (pp *PodPool) Explain(a Pod, b Pod) ([]GraphEdge, error) {
+	state, err := pp.beginOperation()
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { pp.endOperation(state) }()
+
+	if state, err = pp.resolveForGraph(state); err != nil {
+		return nil, err
+	}
+
+	indexA, indexB := -1, -1
+	i := 0
+
+	for pod := pp.firstPod; pod != nil; pod = pod.Next {
+		if pod.Raw == a {
+			indexA = i
+		}
+
+		if pod.Raw == b {
+			indexB = i
+		}
+
+		i++
+	}
+
+	if indexA < 0 {
+		return nil, fmt.Errorf("%w; podType=%q", ErrPodNotFound, reflect.TypeOf(a))
+	}
+
+	if indexB < 0 {
+		return nil, fmt.Errorf("%w; podType=%q", ErrPodNotFound, reflect.TypeOf(b))
+	}
+
+	return makeGraph(pp.firstPod).Explain(indexA, indexB), nil
+}
+
+func (pp *PodPool) resolveForGraph(state PodPoolState) (PodPoolState, error) {
+	if state == PodPoolStateResolved || state == PodPoolStateSetUp {
+		return state, nil
+	}
+
+	if err := pp.resolve(); err != nil {
+		return state, err
+	}
+
+	if state == PodPoolStateTornDown {
+		return state, nil
+	}
+
+	return PodPoolStateResolved, nil
+}
+
+// Explain returns the shortest chain of dependencies which forces the pod at
+// index `a` in Graph.Pods to be set up before the pod at index `b`, i.e. the
+// edges from the pod `a` to the pod `b`, where the `From` of the first edge
+// is `a` and the `To` of the last edge is `b`. It returns nil if there is no
+// such chain, or if `a` or `b` is out of the range of Graph.Pods.
+func (g *Graph) Explain(a int, b int) []GraphEdge {
+	if a < 0 || a >= len(g.Pods) || b < 0 || b >= len(g.Pods) {
+		return nil
+	}
+
+	outEdges := make([][]int, len(g.Pods))
+
+	for i, edge := range g.Edges {
+		outEdges[edge.From] = append(outEdges[edge.From], i)
+	}
+
+	// viaEdges[i] is the index of the edge through which the pod i is
+	// reached first.
+	viaEdges := make([]int, len(g.Pods))
+
+	for i := range viaEdges {
+		viaEdges[i] = -1
+	}
+
+	queue := []int{a}
+
+	for len(queue) >= 1 && viaEdges[b] < 0 {
+		pod := queue[0]
+		queue = queue[1:]
+
+		for _, i := range outEdges[pod] {
+			if to := g.Edges[i].To; to != a && viaEdges[to] < 0 {
+				viaEdges[to] = i
+				queue = append(queue, to)
+			}
+		}
+	}
+
+	if a == b || viaEdges[b] < 0 {
+		return nil
+	}
+
+	var edges []GraphEdge
+
+	for pod := b; pod != a; pod = g.Edges[viaEdges[pod]].From {
+		edges = append(edges, g.Edges[viaEdges[pod]])
+	}
+
+	for i, j := 0, len(edges)-1; i < j; i, j = i+1, j-1 {
+		edges[i], edges[j] = edges[j], edges[i]
+	}
+
+	return edges
+}
+
+func makeGraph(firstPod *pod) *Graph {
+	var graph Graph
+	pod2Index := make(map[*pod]int)
+
+	for pod := firstPod; pod != nil; pod = pod.Next {
+		pod2Index[pod] = len(graph.Pods)
+		graph.Pods = append(graph.Pods, pod.Graph())
+	}
+
+	for pod := firstPod; pod != nil; pod = pod.Next {
+		for i := range pod.ImportEntries {
+			importEntry := &pod.ImportEntries[i]
+			exportEntry := importEntry.ExportEntry
+
+			graph.Edges = append(graph.Edges, GraphEdge{
+				Kind:          GraphEdgeImport,
+				From:          pod2Index[exportEntry.Pod],
+				FromEntryPath: exportEntry.Path,
+				To:            pod2Index[pod],
+				ToEntryPath:   importEntry.Path,
+			})
+		}
+
+		for i := range pod.ExportEntries {
+			exportEntry := &pod.ExportEntries[i]
+
+			for _, filterEntry := range exportEntry.FilterEntries {
+				from, ok := pod2Index[filterEntry.Pod]
+
+				if !ok || filterEntry.Pod == pod {
+					continue
+				}
+
+				graph.Edges = append(graph.Edges, GraphEdge{
+					Kind:          GraphEdgeFilter,
+					From:          from,
+					FromEntryPath: filterEntry.Path,
+					To:            pod2Index[pod],
+					ToEntryPath:   exportEntry.Path,
+				})
+			}
+		}
+	}
+
+	return &graph
+}
+
+func (p *pod) Graph() GraphPod {
+	graphPod := GraphPod{
+		Type: reflect.TypeOf(p.Raw).String(),
+		Name: p.FullName(),
+	}
+
+	for i := range p.ImportEntries {
+		importEntry := &p.ImportEntries[i]
+
+		graphPod.ImportEntries = append(graphPod.ImportEntries, GraphImportEntry{
+			Path:            importEntry.Path,
+			FieldType:       importEntry.FieldType.String(),
+			RefID:           importEntry.RefID,
+			Selector:        importEntry.Selector,
+			ExportEntryPath: importEntry.ExportEntry.Path,
+		})
+	}
+
+	for i := range p.ExportEntries {
+		exportEntry := &p.ExportEntries[i]
+		graphExportEntry := GraphExportEntry{
+			Path:      exportEntry.Path,
+			FieldType: exportEntry.FieldType.String(),
+			RefID:     exportEntry.RefID,
+			Labels:    exportEntry.Labels,
+		}
+
+		for _, importEntry := range exportEntry.ImportEntries {
+			graphExportEntry.ImportEntryPaths = append(graphExportEntry.ImportEntryPaths, importEntry.Path)
+		}
+
+		for _, filterEntry := range exportEntry.FilterEntries {
+			graphExportEntry.FilterEntryPaths = append(graphExportEntry.FilterEntryPaths, filterEntry.Path)
+		}
+
+		graphPod.ExportEntries = append(graphPod.ExportEntries, graphExportEntry)
+	}
+
+	for i := range p.FilterEntries {
+		filterEntry := &p.FilterEntries[i]
+		graphFilterEntry := GraphFilterEntry{
+			Path:      filterEntry.Path,
+			FieldType: filterEntry.FieldType.String(),
+			RefID:     filterEntry.RefID,
+			Selector:  filterEntry.Selector,
+			Priority:  filterEntry.Priority,
+		}
+
+		for _, exportEntry := range filterEntry.ExportEntries {
+			graphFilterEntry.ExportEntryPaths = append(graphFilterEntry.ExportEntryPaths, exportEntry.Path)
+		}
+
+		graphPod.FilterEntries = append(graphPod.FilterEntries, graphFilterEntry)
+	}
+
+	return graphPod
+}
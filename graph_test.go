@@ -0,0 +1,207 @@
+package depinj_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/depinj"
+)
+
+type podO1 struct {
+	depinj.DummyPod
+	PrimaryEU   string `export:"db_1,labels=region:eu;role:primary"`
+	ReplicaEU   string `export:"db_2,labels=region:eu;role:replica"`
+	PrimaryUS   string `export:"db_3,labels=region:us;role:primary"`
+	Unlabeled   string `export:"db_4"`
+	UnlabeledID int    `export:""`
+}
+
+func (p *podO1) SetUp(context.Context) error {
+	p.PrimaryEU, p.ReplicaEU, p.PrimaryUS = "primary eu", "replica eu", "primary us"
+	return nil
+}
+
+type podO2 struct {
+	depinj.DummyPod
+	DB   string `import:",select=role:primary;region:eu"`
+	Size int    `import:""`
+	T    *testing.T
+}
+
+func (p *podO2) SetUp(context.Context) error {
+	assert.Equal(p.T, "primary eu", p.DB)
+	return nil
+}
+
+type podO6 struct {
+	depinj.DummyPod
+	DB *string `filter:",Tag,0,select=role:replica"`
+}
+
+func (p *podO6) Tag(_ context.Context, db string) (string, error) { return db + " (tagged)", nil }
+
+type podO3 struct {
+	depinj.DummyPod
+	DB string `import:",select=role:primary"`
+}
+
+type podO4 struct {
+	depinj.DummyPod
+	DB string `import:",select=role:standby"`
+}
+
+type podO5 struct {
+	depinj.DummyPod
+	DB string `export:"db,labels=role"`
+}
+
+func TestLabels(t *testing.T) {
+	var pp depinj.PodPool
+	p1 := &podO1{}
+	for _, p := range []depinj.Pod{&podO2{T: t}, p1, &podO6{}} {
+		err := pp.AddPod(p)
+		assert.NoError(t, err)
+	}
+	graph, err := pp.Graph()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, &depinj.Graph{
+		Pods: []depinj.GraphPod{
+			{
+				Type: "*depinj_test.podO6",
+				FilterEntries: []depinj.GraphFilterEntry{
+					{Path: "depinj_test.podO6.DB", FieldType: "*string", Selector: map[string]string{"role": "replica"}, ExportEntryPaths: []string{"depinj_test.podO1.ReplicaEU"}},
+				},
+			},
+			{
+				Type: "*depinj_test.podO1",
+				ExportEntries: []depinj.GraphExportEntry{
+					{Path: "depinj_test.podO1.PrimaryEU", FieldType: "string", RefID: "db_1", Labels: map[string]string{"region": "eu", "role": "primary"}, ImportEntryPaths: []string{"depinj_test.podO2.DB"}},
+					{Path: "depinj_test.podO1.ReplicaEU", FieldType: "string", RefID: "db_2", Labels: map[string]string{"region": "eu", "role": "replica"}, FilterEntryPaths: []string{"depinj_test.podO6.DB"}},
+					{Path: "depinj_test.podO1.PrimaryUS", FieldType: "string", RefID: "db_3", Labels: map[string]string{"region": "us", "role": "primary"}},
+					{Path: "depinj_test.podO1.Unlabeled", FieldType: "string", RefID: "db_4"},
+					{Path: "depinj_test.podO1.UnlabeledID", FieldType: "int", ImportEntryPaths: []string{"depinj_test.podO2.Size"}},
+				},
+			},
+			{
+				Type: "*depinj_test.podO2",
+				ImportEntries: []depinj.GraphImportEntry{
+					{Path: "depinj_test.podO2.DB", FieldType: "string", Selector: map[string]string{"region": "eu", "role": "primary"}, ExportEntryPath: "depinj_test.podO1.PrimaryEU"},
+					{Path: "depinj_test.podO2.Size", FieldType: "int", ExportEntryPath: "depinj_test.podO1.UnlabeledID"},
+				},
+			},
+		},
+		Edges: []depinj.GraphEdge{
+			{Kind: depinj.GraphEdgeFilter, From: 0, FromEntryPath: "depinj_test.podO6.DB", To: 1, ToEntryPath: "depinj_test.podO1.ReplicaEU"},
+			{Kind: depinj.GraphEdgeImport, From: 1, FromEntryPath: "depinj_test.podO1.PrimaryEU", To: 2, ToEntryPath: "depinj_test.podO2.DB"},
+			{Kind: depinj.GraphEdgeImport, From: 1, FromEntryPath: "depinj_test.podO1.UnlabeledID", To: 2, ToEntryPath: "depinj_test.podO2.Size"},
+		},
+	}, graph)
+	assert.Len(t, graph.FindExportEntriesByLabels(map[string]string{"role": "primary"}), 2)
+	err = pp.SetUp(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "replica eu (tagged)", p1.ReplicaEU)
+	pp.TearDown()
+}
+
+func TestLabelsFailed(t *testing.T) {
+	for _, tt := range []struct {
+		Pods   []depinj.Pod
+		Err    error
+		ErrMsg string
+	}{
+		{[]depinj.Pod{&podO1{}, &podO3{}}, depinj.ErrBadImportEntry, "depinj: bad import entry: ambiguous export entries by labels; importEntryPath=\"depinj_test.podO3.DB\" fieldType=\"string\" selector=\"role:primary\" exportEntryPaths=[\"depinj_test.podO1.PrimaryEU\" \"depinj_test.podO1.PrimaryUS\"]"},
+		{[]depinj.Pod{&podO1{}, &podO4{}}, depinj.ErrBadImportEntry, "depinj: bad import entry: export entry not found by labels; importEntryPath=\"depinj_test.podO4.DB\" fieldType=\"string\" selector=\"role:standby\""},
+		{[]depinj.Pod{&podO5{}}, depinj.ErrBadExportEntry, "depinj: bad export entry: labels parse failed; exportEntryPath=\"depinj_test.podO5.DB\" option=\"labels=role\": bad label \"role\", expected `<key>:<value>`"},
+	} {
+		var pp depinj.PodPool
+		var err error
+		for _, p := range tt.Pods {
+			if err = pp.AddPod(p); err != nil {
+				break
+			}
+		}
+		if err == nil {
+			err = pp.SetUp(context.Background())
+		}
+		assert.True(t, errors.Is(err, tt.Err))
+		assert.EqualError(t, err, tt.ErrMsg)
+	}
+}
+
+type podU1 struct {
+	depinj.DummyPod
+	Foo string `export:"foo"`
+}
+
+type podU2 struct {
+	depinj.DummyPod
+	Foo string `import:"foo"`
+	Bar int    `export:"bar"`
+}
+
+type podU3 struct {
+	depinj.DummyPod
+	Bar int `import:"bar"`
+}
+
+type podU4 struct {
+	depinj.DummyPod
+	Foo *string `filter:"foo,Tag,0"`
+}
+
+func (p *podU4) Tag(_ context.Context, foo string) (string, error) { return foo, nil }
+
+func TestExplain(t *testing.T) {
+	var pp depinj.PodPool
+	p1, p2, p3, p4 := &podU1{}, &podU2{}, &podU3{}, &podU4{}
+	for _, p := range []depinj.Pod{p3, p2, p1, p4} {
+		pp.MustAddPod(p)
+	}
+
+	edges, err := pp.Explain(p4, p3)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []depinj.GraphEdge{
+			{Kind: depinj.GraphEdgeFilter, From: 0, FromEntryPath: "depinj_test.podU4.Foo", To: 1, ToEntryPath: "depinj_test.podU1.Foo"},
+			{Kind: depinj.GraphEdgeImport, From: 1, FromEntryPath: "depinj_test.podU1.Foo", To: 2, ToEntryPath: "depinj_test.podU2.Foo"},
+			{Kind: depinj.GraphEdgeImport, From: 2, FromEntryPath: "depinj_test.podU2.Bar", To: 3, ToEntryPath: "depinj_test.podU3.Bar"},
+		}, edges)
+	}
+	assert.Equal(t, depinj.PodPoolStateResolved, pp.State())
+
+	edges, err = pp.Explain(p3, p1)
+	assert.NoError(t, err)
+	assert.Nil(t, edges)
+	edges, err = pp.Explain(p1, p1)
+	assert.NoError(t, err)
+	assert.Nil(t, edges)
+
+	_, err = pp.Explain(p1, &podU3{})
+	assert.True(t, errors.Is(err, depinj.ErrPodNotFound), "%v", err)
+
+	pp.MustSetUp(context.Background())
+	edges, err = pp.Explain(p1, p3)
+	assert.NoError(t, err)
+	assert.Len(t, edges, 2)
+
+	graph, err := pp.Graph()
+	if assert.NoError(t, err) {
+		assert.Equal(t, edges, graph.Explain(1, 3))
+		assert.Nil(t, graph.Explain(3, 0))
+		assert.Nil(t, graph.Explain(-1, 3))
+		assert.Nil(t, graph.Explain(1, 4))
+	}
+
+	pp.MustTearDown()
+	graph, err = pp.Graph()
+	if assert.NoError(t, err) {
+		assert.Len(t, graph.Pods, 4)
+	}
+	assert.Equal(t, depinj.PodPoolStateTornDown, pp.State())
+	err = pp.TryTearDown()
+	assert.True(t, errors.Is(err, depinj.ErrPodPoolTornDown), "%v", err)
+}
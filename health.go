@@ -0,0 +1,200 @@
+package depinj
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// HealthChecker is an optional interface of Pod.
+type HealthChecker interface {
+	// CheckHealth checks the health of the pod. It returns an error if the
+	// pod is unhealthy. It's called concurrently with the other pods' and
+	// should return before the context is done.
+	CheckHealth(ctx context.Context) (err error)
+}
+
+// DefaultHealthCheckTimeout is the default timeout of each health check.
+const DefaultHealthCheckTimeout = 5 * time.Second
+
+// HealthReport represents the result of the health checks of a pool.
+type HealthReport struct {
+	// IsHealthy is true if all the pods are healthy.
+	IsHealthy bool `json:"healthy"`
+
+	// IsReady is true if all the pods have been set up.
+	IsReady bool `json:"ready"`
+
+	// Pods are the results of the health checks of the pods implementing
+	// HealthChecker, in the order of setups. The pods which haven't been set
+	// up are not checked.
+	Pods []PodHealth `json:"pods"`
+}
+
+// PodHealth represents the result of the health check of a pod.
+type PodHealth struct {
+	PodType   string        `json:"podType"`
+	PodName   string        `json:"podName,omitempty"`
+	RefIDs    []string      `json:"refIDs,omitempty"`
+	IsHealthy bool          `json:"healthy"`
+	Error     string        `json:"error,omitempty"`
+	Duration  time.Duration `json:"duration"`
+}
+
+// SetHealthCheckTimeout sets the timeout of each health check,
+// DefaultHealthCheckTimeout is used if it's not set.
+func (pp *PodPool) SetHealthCheckTimeout(healthCheckTimeout time.Duration) {
+	pp.mutex.Lock()
+	defer pp.mutex.Unlock()
+	pp.healthCheckTimeout = healthCheckTimeout
+}
+
+// CheckHealth checks the health of all the pods implementing HealthChecker
+// in the pool concurrently, each check with a timeout, and returns a report.
+// Only the pods which have been set up are checked, and the pool isn't ready
+// unless all the pods have been set up, e.g. after PodPool.Graph or
+// PodPool.TearDown. While the pool is busy, e.g. restarting pods, no check is
+// made and the pool is neither healthy nor ready, since the pods may have been
+// torn down.
+func (pp *PodPool) CheckHealth(ctx context.Context) HealthReport {
+	healthCheckTimeout, report, healthCheckers := pp.prepareHealthChecks()
+
+	if healthCheckTimeout <= 0 {
+		healthCheckTimeout = DefaultHealthCheckTimeout
+	}
+
+	var waitGroup sync.WaitGroup
+
+	for i := range healthCheckers {
+		waitGroup.Add(1)
+
+		go func(healthChecker HealthChecker, podHealth *PodHealth) {
+			defer waitGroup.Done()
+			ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+			defer cancel()
+			t := time.Now()
+			err := checkHealth(ctx, healthChecker)
+			podHealth.Duration = time.Since(t)
+
+			if err != nil {
+				podHealth.Error = err.Error()
+				return
+			}
+
+			podHealth.IsHealthy = true
+		}(healthCheckers[i], &report.Pods[i])
+	}
+
+	waitGroup.Wait()
+
+	for i := range report.Pods {
+		if !report.Pods[i].IsHealthy {
+			report.IsHealthy = false
+			break
+		}
+	}
+
+	return report
+}
+
+// HealthHandler returns an http.Handler, e.g. for `/healthz`, which responds
+// with the report of the health checks in JSON. The status code is 200 if all
+// the pods are healthy, otherwise 503.
+func (pp *PodPool) HealthHandler() http.Handler {
+	return http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		report := pp.CheckHealth(request.Context())
+		writeHealthReport(responseWriter, report, report.IsHealthy)
+	})
+}
+
+// ReadinessHandler returns an http.Handler, e.g. for `/readyz`, which responds
+// with the report of the health checks in JSON. The status code is 200 if all
+// the pods have been set up and are healthy, otherwise 503.
+func (pp *PodPool) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		report := pp.CheckHealth(request.Context())
+		writeHealthReport(responseWriter, report, report.IsReady && report.IsHealthy)
+	})
+}
+
+func (pp *PodPool) prepareHealthChecks() (time.Duration, HealthReport, []HealthChecker) {
+	pp.mutex.Lock()
+	defer pp.mutex.Unlock()
+
+	if pp.isBusy {
+		return pp.healthCheckTimeout, HealthReport{}, nil
+	}
+
+	report := HealthReport{
+		IsHealthy: true,
+	}
+
+	report.IsReady = pp.state == PodPoolStateSetUp
+	var healthCheckers []HealthChecker
+
+	for pod := pp.firstPod; pod != nil; pod = pod.Next {
+		if !pod.IsSetUp {
+			report.IsReady = false
+			continue
+		}
+
+		healthChecker, ok := pod.Raw.(HealthChecker)
+
+		if !ok {
+			continue
+		}
+
+		healthCheckers = append(healthCheckers, healthChecker)
+		report.Pods = append(report.Pods, pod.Health())
+	}
+
+	return pp.healthCheckTimeout, report, healthCheckers
+}
+
+func (p *pod) Health() PodHealth {
+	podHealth := PodHealth{
+		PodType: reflect.TypeOf(p.Raw).String(),
+		PodName: p.FullName(),
+	}
+
+	for i := range p.ExportEntries {
+		exportEntry := &p.ExportEntries[i]
+
+		if exportEntry.RefID != "" {
+			podHealth.RefIDs = append(podHealth.RefIDs, exportEntry.RefID)
+		}
+	}
+
+	return podHealth
+}
+
+func checkHealth(ctx context.Context, healthChecker HealthChecker) error {
+	errs := make(chan error, 1)
+
+	go func() {
+		errs <- healthChecker.CheckHealth(ctx)
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("depinj: health check timed out: %w", ctx.Err())
+	}
+}
+
+func writeHealthReport(responseWriter http.ResponseWriter, report HealthReport, ok bool) {
+	responseWriter.Header().Set("Content-Type", "application/json")
+
+	if ok {
+		responseWriter.WriteHeader(http.StatusOK)
+	} else {
+		responseWriter.WriteHeader(http.StatusServiceUnavailable)
+	}
+
+	json.NewEncoder(responseWriter).Encode(report)
+}
@@ -0,0 +1,150 @@
+package depinj_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/depinj"
+)
+
+type podQ1 struct {
+	depinj.DummyPod
+	DB  string `export:"db"`
+	Err error
+}
+
+func (p *podQ1) CheckHealth(context.Context) error { return p.Err }
+
+type podQ2 struct {
+	depinj.DummyPod
+	DB string `import:"db"`
+}
+
+func (p *podQ2) CheckHealth(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestCheckHealth(t *testing.T) {
+	var pp depinj.PodPool
+	pp.SetHealthCheckTimeout(10 * time.Millisecond)
+	p1 := &podQ1{}
+	for _, p := range []depinj.Pod{p1, &podQ2{}} {
+		err := pp.AddPod(p)
+		assert.NoError(t, err)
+	}
+
+	responseRecorder := httptest.NewRecorder()
+	pp.ReadinessHandler().ServeHTTP(responseRecorder, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, responseRecorder.Code)
+
+	err := pp.SetUp(context.Background())
+	assert.NoError(t, err)
+	defer pp.TearDown()
+	report := pp.CheckHealth(context.Background())
+	assert.True(t, report.IsReady)
+	assert.False(t, report.IsHealthy)
+	if assert.Len(t, report.Pods, 2) {
+		assert.Equal(t, "*depinj_test.podQ1", report.Pods[0].PodType)
+		assert.Equal(t, []string{"db"}, report.Pods[0].RefIDs)
+		assert.True(t, report.Pods[0].IsHealthy)
+		assert.Equal(t, "*depinj_test.podQ2", report.Pods[1].PodType)
+		assert.False(t, report.Pods[1].IsHealthy)
+		assert.Contains(t, report.Pods[1].Error, "context deadline exceeded")
+	}
+
+	p1.Err = errors.New("connection refused")
+	responseRecorder = httptest.NewRecorder()
+	pp.HealthHandler().ServeHTTP(responseRecorder, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, responseRecorder.Code)
+	assert.Equal(t, "application/json", responseRecorder.Header().Get("Content-Type"))
+	var report2 depinj.HealthReport
+	err = json.Unmarshal(responseRecorder.Body.Bytes(), &report2)
+	assert.NoError(t, err)
+	if assert.Len(t, report2.Pods, 2) {
+		assert.Equal(t, "connection refused", report2.Pods[0].Error)
+	}
+}
+
+type podQ3 struct {
+	depinj.DummyPod
+	DB      string `export:"db"`
+	OnSetUp func()
+}
+
+func (p *podQ3) SetUp(context.Context) error {
+	p.OnSetUp()
+	return nil
+}
+
+func TestCheckHealthWhileBusy(t *testing.T) {
+	var pp depinj.PodPool
+	p1 := &podQ3{OnSetUp: func() {}}
+	pp.MustAddPod(p1)
+	pp.MustSetUp(context.Background())
+	defer pp.TearDown()
+
+	var codes []int
+	p1.OnSetUp = func() {
+		responseRecorder := httptest.NewRecorder()
+		pp.HealthHandler().ServeHTTP(responseRecorder, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+		codes = append(codes, responseRecorder.Code)
+	}
+	err := pp.Restart(context.Background(), p1)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{http.StatusServiceUnavailable}, codes)
+
+	responseRecorder := httptest.NewRecorder()
+	pp.HealthHandler().ServeHTTP(responseRecorder, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+}
+
+type podQ4 struct {
+	depinj.DummyPod
+	DB   string `export:"db"`
+	conn *string
+}
+
+func (p *podQ4) SetUp(context.Context) error {
+	conn := "conn"
+	p.conn = &conn
+	return nil
+}
+
+func (p *podQ4) TearDown() { p.conn = nil }
+
+func (p *podQ4) CheckHealth(context.Context) error {
+	if *p.conn != "conn" {
+		return errors.New("bad connection")
+	}
+
+	return nil
+}
+
+func TestCheckHealthNotSetUp(t *testing.T) {
+	var pp depinj.PodPool
+	pp.MustAddPod(&podQ4{})
+	_, err := pp.Graph()
+	assert.NoError(t, err)
+	report := pp.CheckHealth(context.Background())
+	assert.False(t, report.IsReady)
+	assert.Empty(t, report.Pods)
+
+	pp.MustSetUp(context.Background())
+	report = pp.CheckHealth(context.Background())
+	assert.True(t, report.IsReady)
+	assert.True(t, report.IsHealthy)
+	assert.Len(t, report.Pods, 1)
+
+	pp.MustTearDown()
+	report = pp.CheckHealth(context.Background())
+	assert.False(t, report.IsReady)
+	assert.Empty(t, report.Pods)
+}
@@ -0,0 +1,222 @@
+// Package tagsyntax implements the syntax of the struct tags of pods shared by
+// depinj and its static checker, i.e. options, labels, ref ids and the order of
+// filters.
+package tagsyntax
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// SplitOption splits the given option in the form of `<key>=<value>` into the
+// key and the value. The value is empty if there is no `=`.
+func SplitOption(option string) (string, string) {
+	if i := strings.IndexByte(option, '='); i >= 0 {
+		return option[:i], option[i+1:]
+	}
+
+	return option, ""
+}
+
+// ParseLabels parses the given labels in the form of
+// `<key>:<value>;<key>:<value>...`.
+func ParseLabels(str string) (map[string]string, error) {
+	labels := make(map[string]string)
+
+	for _, label := range strings.Split(str, ";") {
+		i := strings.IndexByte(label, ':')
+
+		if i < 1 {
+			return nil, fmt.Errorf("bad label %q, expected `<key>:<value>`", label)
+		}
+
+		labels[label[:i]] = label[i+1:]
+	}
+
+	return labels, nil
+}
+
+// HasLabels reports whether the given labels contain all the given sub labels.
+func HasLabels(labels map[string]string, subLabels map[string]string) bool {
+	for key, value := range subLabels {
+		if value2, ok := labels[key]; !ok || value2 != value {
+			return false
+		}
+	}
+
+	return true
+}
+
+// FormatLabels formats the given labels as ParseLabels parses them, with the
+// keys sorted.
+func FormatLabels(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+
+	for key := range labels {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+	var buffer bytes.Buffer
+
+	for i, key := range keys {
+		if i >= 1 {
+			buffer.WriteByte(';')
+		}
+
+		buffer.WriteString(key)
+		buffer.WriteByte(':')
+		buffer.WriteString(labels[key])
+	}
+
+	return buffer.String()
+}
+
+// IsRefLink reports whether the given ref id is a ref link, i.e. starts with
+// `@`.
+func IsRefLink(refID string) bool {
+	return len(refID) >= 1 && refID[0] == '@'
+}
+
+// MatchRefID reports whether the given ref id matches the given pattern, as
+// path.Match does, except that `**` matches any sequence of characters,
+// including `/`.
+func MatchRefID(pattern string, refID string) bool {
+	i := strings.Index(pattern, "**")
+
+	if i < 0 {
+		ok, _ := path.Match(pattern, refID)
+		return ok
+	}
+
+	prefix, suffix := pattern[:i], pattern[i+2:]
+
+	for j := 0; j <= len(refID); j++ {
+		if ok, _ := path.Match(prefix, refID[:j]); !ok {
+			continue
+		}
+
+		// `**` matches refID[j:k]
+		for k := j; k <= len(refID); k++ {
+			if MatchRefID(suffix, refID[k:]) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Filter represents a filter to be sorted by SortFilters.
+type Filter struct {
+	Path      string
+	ID        string
+	Priority  int
+	Seq       int
+	BeforeIDs []string
+	AfterIDs  []string
+}
+
+// HasID reports whether the filter is identified by the given id, i.e. its
+// path or its id.
+func (f *Filter) HasID(id string) bool {
+	return id == f.Path || (f.ID != "" && id == f.ID)
+}
+
+// SortFilters sorts the given filters by their priorities in descending order
+// and then by their sequence numbers in ascending order, and then reorders
+// them as their before/after constraints require, and returns the indexes of
+// the filters in the sorted order. If the constraints are circular, it returns
+// nil along with the paths of the filters which can't be sorted.
+func SortFilters(filters []Filter) ([]int, []string) {
+	n := len(filters)
+	indexes := make([]int, n)
+
+	for i := range indexes {
+		indexes[i] = i
+	}
+
+	sort.Slice(indexes, func(i, j int) bool {
+		filter1, filter2 := &filters[indexes[i]], &filters[indexes[j]]
+
+		if filter1.Priority != filter2.Priority {
+			return filter1.Priority > filter2.Priority
+		}
+
+		return filter1.Seq < filter2.Seq
+	})
+
+	successors := make([][]int, n)
+	inDegrees := make([]int, n)
+	hasConstraints := false
+
+	for i, index := range indexes {
+		filter := &filters[index]
+
+		for j, otherIndex := range indexes {
+			if j == i {
+				continue
+			}
+
+			other := &filters[otherIndex]
+
+			for _, id := range filter.BeforeIDs {
+				if other.HasID(id) {
+					successors[i] = append(successors[i], j)
+					inDegrees[j]++
+					hasConstraints = true
+				}
+			}
+
+			for _, id := range filter.AfterIDs {
+				if other.HasID(id) {
+					successors[j] = append(successors[j], i)
+					inDegrees[i]++
+					hasConstraints = true
+				}
+			}
+		}
+	}
+
+	if !hasConstraints {
+		return indexes, nil
+	}
+
+	// topological sort, which prefers the filters sorted earlier
+	sortedIndexes := make([]int, 0, n)
+	isSorted := make([]bool, n)
+
+	for len(sortedIndexes) < n {
+		i := 0
+
+		for ; i < n; i++ {
+			if !isSorted[i] && inDegrees[i] == 0 {
+				break
+			}
+		}
+
+		if i == n {
+			var filterPaths []string
+
+			for i, index := range indexes {
+				if !isSorted[i] {
+					filterPaths = append(filterPaths, filters[index].Path)
+				}
+			}
+
+			return nil, filterPaths
+		}
+
+		isSorted[i] = true
+		sortedIndexes = append(sortedIndexes, indexes[i])
+
+		for _, j := range successors[i] {
+			inDegrees[j]--
+		}
+	}
+
+	return sortedIndexes, nil
+}
@@ -0,0 +1,48 @@
+package tagsyntax_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/depinj/internal/tagsyntax"
+)
+
+func TestLabels(t *testing.T) {
+	labels, err := tagsyntax.ParseLabels("b:2;a:1")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, map[string]string{"a": "1", "b": "2"}, labels)
+	assert.Equal(t, "a:1;b:2", tagsyntax.FormatLabels(labels))
+	assert.True(t, tagsyntax.HasLabels(labels, map[string]string{"a": "1"}))
+	assert.False(t, tagsyntax.HasLabels(labels, map[string]string{"a": "2"}))
+	_, err = tagsyntax.ParseLabels("a:1;:2")
+	assert.EqualError(t, err, "bad label \":2\", expected `<key>:<value>`")
+}
+
+func TestMatchRefID(t *testing.T) {
+	assert.True(t, tagsyntax.MatchRefID("foo/*", "foo/bar"))
+	assert.False(t, tagsyntax.MatchRefID("foo/*", "foo/bar/baz"))
+	assert.True(t, tagsyntax.MatchRefID("foo/**", "foo/bar/baz"))
+	assert.True(t, tagsyntax.MatchRefID("**/baz", "foo/bar/baz"))
+	assert.False(t, tagsyntax.MatchRefID("**/qux", "foo/bar/baz"))
+}
+
+func TestSortFilters(t *testing.T) {
+	indexes, paths := tagsyntax.SortFilters([]tagsyntax.Filter{
+		{Path: "a", Priority: 0, Seq: 0},
+		{Path: "b", Priority: 1, Seq: 1},
+		{Path: "c", ID: "c1", Priority: 0, Seq: 2, BeforeIDs: []string{"b"}},
+	})
+	assert.Nil(t, paths)
+	assert.Equal(t, []int{0, 2, 1}, indexes)
+
+	indexes, paths = tagsyntax.SortFilters([]tagsyntax.Filter{
+		{Path: "a", Seq: 0, AfterIDs: []string{"b1"}},
+		{Path: "b", ID: "b1", Seq: 1, AfterIDs: []string{"a"}},
+		{Path: "c", Seq: 2},
+	})
+	assert.Nil(t, indexes)
+	assert.Equal(t, []string{"a", "b"}, paths)
+}
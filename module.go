@@ -0,0 +1,271 @@
+package depinj
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/roy2220/depinj/internal/tagsyntax"
+)
+
+// Module represents a reusable bundle of pods with private wiring. The export
+// entries of the pods in a module are private to the module by default, i.e.
+// they are visible only to the import/filter entries of the pods in the
+// module (including the sub-modules), and shadow the export entries with the
+// same ref ids or field types outside the module. Only the export entries
+// published by Publish or PublishFieldTypes are visible to the parent module,
+// or to the pool if the module is added to the pool directly.
+//
+// A module is added to a pool by PodPool.AddModule, and the zero value is an
+// empty module without a name.
+type Module struct {
+	// Name is the name of the module, which must not be empty. The names of
+	// the module and its parent modules, joined by `/`, make up the module
+	// path, e.g. `app/postgres`, which prefixes the paths of the entries in
+	// the pods of the module, in the form of `<module path>/<path>`, and names
+	// the pods of the module in errors.
+	Name string
+
+	members          []moduleMember
+	publicRefIDs     []string
+	publicFieldTypes []reflect.Type
+}
+
+type moduleMember struct {
+	PodName string
+	RawPod  Pod
+	Module  *Module
+}
+
+// AddPod adds the given pod to the module. The pod isn't checked until the
+// module is added to a pool.
+func (m *Module) AddPod(rawPod Pod) {
+	m.members = append(m.members, moduleMember{RawPod: rawPod})
+}
+
+// AddNamedPod adds the given pod to the module with the given name, see
+// PodPool.AddNamedPod.
+func (m *Module) AddNamedPod(name string, rawPod Pod) {
+	m.members = append(m.members, moduleMember{PodName: name, RawPod: rawPod})
+}
+
+// AddModule adds the given module to the module as a sub-module.
+func (m *Module) AddModule(module *Module) {
+	m.members = append(m.members, moduleMember{Module: module})
+}
+
+// Publish makes the export entries with the given ref ids visible outside the
+// module. The ref ids are the ones seen inside the module, i.e. namespaced by
+// Namespacer and prefixed with the names of the named pods, if any. The ref
+// ids matching no export entry of the module (including the ones published by
+// the sub-modules) fail PodPool.AddModule, unless the module has export
+// entries with ref links resolved by the pool, whose ref ids are unknown until
+// the setup.
+func (m *Module) Publish(refIDs ...string) {
+	m.publicRefIDs = append(m.publicRefIDs, refIDs...)
+}
+
+// PublishFieldTypes makes the export entries without ref ids and with the
+// given field types visible outside the module. The field types matching no
+// export entry of the module (including the ones published by the
+// sub-modules) fail PodPool.AddModule.
+func (m *Module) PublishFieldTypes(fieldTypes ...reflect.Type) {
+	m.publicFieldTypes = append(m.publicFieldTypes, fieldTypes...)
+}
+
+// AddModule adds the pods of the given module, including the sub-modules, to
+// the pool. The module is checked as a whole, i.e. if any pod is invalid, no
+// pod of the module is added.
+func (pp *PodPool) AddModule(module *Module) error {
+	var pods []*pod
+
+	if err := module.flatten(nil, nil, make(map[*Module]struct{}), &pods); err != nil {
+		return err
+	}
+
+	return pp.addPods(pods, module.Name)
+}
+
+// MustAddModule adds the pods of the given module to the pool, it panics if
+// any error occurs.
+func (pp *PodPool) MustAddModule(module *Module) {
+	if err := pp.AddModule(module); err != nil {
+		panic(err)
+	}
+}
+
+func (m *Module) flatten(parent *moduleScope, parentExports *moduleExports, visitedModules map[*Module]struct{}, pods *[]*pod) error {
+	scope := new(moduleScope).Init(parent, m)
+
+	if m.Name == "" {
+		return fmt.Errorf("%w: empty module name; parentModulePath=%q", ErrInvalidModule, parent.GetPath())
+	}
+
+	if _, ok := visitedModules[m]; ok {
+		return fmt.Errorf("%w: module cycle; modulePath=%q", ErrInvalidModule, scope.Path)
+	}
+
+	visitedModules[m] = struct{}{}
+	defer delete(visitedModules, m)
+	exports := new(moduleExports).Init()
+
+	for _, member := range m.members {
+		if member.Module != nil {
+			if err := member.Module.flatten(scope, exports, visitedModules, pods); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		pod := &pod{Name: member.PodName, Module: scope}
+
+		if err := pod.ParseRaw(member.RawPod); err != nil {
+			return fmt.Errorf("%w; modulePath=%q", err, scope.Path)
+		}
+
+		exports.AddPod(pod)
+		*pods = append(*pods, pod)
+	}
+
+	for _, refID := range m.publicRefIDs {
+		if _, ok := exports.RefIDs[refID]; !ok && !exports.HasUnknownRefIDs {
+			return fmt.Errorf("%w: published ref id not exported; modulePath=%q refID=%q", ErrInvalidModule, scope.Path, refID)
+		}
+	}
+
+	for _, fieldType := range m.publicFieldTypes {
+		if _, ok := exports.FieldTypes[fieldType]; !ok {
+			return fmt.Errorf("%w: published field type not exported; modulePath=%q fieldType=%q", ErrInvalidModule, scope.Path, fieldType)
+		}
+	}
+
+	if parentExports != nil {
+		parentExports.AddPublished(m)
+	}
+
+	return nil
+}
+
+// moduleExports is the set of the ref ids and the field types of the export
+// entries visible in a module, for the check of the published ones.
+type moduleExports struct {
+	RefIDs     map[string]struct{}
+	FieldTypes map[reflect.Type]struct{}
+
+	// HasUnknownRefIDs is set if any export entry has a ref link resolved by
+	// the pool, whose ref id is unknown until the setup.
+	HasUnknownRefIDs bool
+}
+
+func (me *moduleExports) Init() *moduleExports {
+	me.RefIDs = make(map[string]struct{})
+	me.FieldTypes = make(map[reflect.Type]struct{})
+	return me
+}
+
+func (me *moduleExports) AddPod(pod *pod) {
+	for i := range pod.ExportEntries {
+		exportEntry := &pod.ExportEntries[i]
+		refID := exportEntry.RawRefID
+
+		switch {
+		case refID == "":
+			me.FieldTypes[exportEntry.FieldType] = struct{}{}
+			continue
+		case tagsyntax.IsRefLink(refID):
+			var ok bool
+
+			if refID, ok = pod.Raw.ResolveRefLink(refID); !ok {
+				me.HasUnknownRefIDs = true
+				continue
+			}
+		default:
+			refID = pod.NamespaceRefID(refID)
+		}
+
+		me.RefIDs[refID] = struct{}{}
+	}
+}
+
+// AddPublished adds the ref ids and the field types published by the given
+// sub-module, which are visible in the module.
+func (me *moduleExports) AddPublished(subModule *Module) {
+	for _, refID := range subModule.publicRefIDs {
+		me.RefIDs[refID] = struct{}{}
+	}
+
+	for _, fieldType := range subModule.publicFieldTypes {
+		me.FieldTypes[fieldType] = struct{}{}
+	}
+}
+
+// moduleScope is the scope of the export entries of the pods in a module. The
+// nil scope is the scope of the pool.
+type moduleScope struct {
+	Parent           *moduleScope
+	Path             string
+	PublicRefIDs     map[string]struct{}
+	PublicFieldTypes map[reflect.Type]struct{}
+}
+
+func (ms *moduleScope) Init(parent *moduleScope, module *Module) *moduleScope {
+	ms.Parent = parent
+	ms.Path = parent.JoinPath(module.Name)
+	ms.PublicRefIDs = make(map[string]struct{}, len(module.publicRefIDs))
+
+	for _, refID := range module.publicRefIDs {
+		ms.PublicRefIDs[refID] = struct{}{}
+	}
+
+	ms.PublicFieldTypes = make(map[reflect.Type]struct{}, len(module.publicFieldTypes))
+
+	for _, fieldType := range module.publicFieldTypes {
+		ms.PublicFieldTypes[fieldType] = struct{}{}
+	}
+
+	return ms
+}
+
+func (ms *moduleScope) GetPath() string {
+	if ms == nil {
+		return ""
+	}
+
+	return ms.Path
+}
+
+func (ms *moduleScope) JoinPath(name string) string {
+	if ms == nil {
+		return name
+	}
+
+	if name == "" {
+		return ms.Path
+	}
+
+	return ms.Path + "/" + name
+}
+
+// Publishes returns whether the module publishes the given export entry to
+// the parent scope.
+func (ms *moduleScope) Publishes(exportEntry *exportEntry) bool {
+	if exportEntry.RefID == "" {
+		_, ok := ms.PublicFieldTypes[exportEntry.FieldType]
+		return ok
+	}
+
+	_, ok := ms.PublicRefIDs[exportEntry.RefID]
+	return ok
+}
+
+// Encloses returns whether the given scope is the scope itself or nested in
+// the scope.
+func (ms *moduleScope) Encloses(other *moduleScope) bool {
+	for ; other != nil; other = other.Parent {
+		if other == ms {
+			return true
+		}
+	}
+
+	return ms == nil
+}
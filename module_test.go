@@ -0,0 +1,151 @@
+package depinj_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/roy2220/depinj"
+	"github.com/stretchr/testify/assert"
+)
+
+type podY1 struct {
+	depinj.DummyPod
+	Conn string `export:"conn"`
+}
+
+func (p *podY1) SetUp(context.Context) error { p.Conn = p.Conn + "-conn"; return nil }
+
+type podY2 struct {
+	depinj.DummyPod
+	Conn string `import:"conn"`
+	DB   string `export:"db"`
+}
+
+func (p *podY2) SetUp(context.Context) error { p.DB = "db of " + p.Conn; return nil }
+
+type podY3 struct {
+	depinj.DummyPod
+	DB string `import:"db"`
+	T  *testing.T
+}
+
+func (p *podY3) SetUp(context.Context) error {
+	assert.Equal(p.T, "db of postgres-conn", p.DB)
+	return nil
+}
+
+type podY4 struct {
+	depinj.DummyPod
+	Conn string `import:"conn"`
+}
+
+type podY5 struct {
+	depinj.DummyPod
+	Conn string `export:"@conn"`
+}
+
+func newPostgresModule() *depinj.Module {
+	module := &depinj.Module{Name: "postgres"}
+	module.AddPod(&podY1{Conn: "postgres"})
+	module.AddPod(&podY2{})
+	module.Publish("db")
+	return module
+}
+
+func TestAddModule(t *testing.T) {
+	var pp depinj.PodPool
+	pp.MustAddModule(newPostgresModule())
+	cacheModule := &depinj.Module{Name: "cache"}
+	cacheModule.AddPod(&podY1{Conn: "cache"})
+	appModule := &depinj.Module{Name: "app"}
+	appModule.AddModule(cacheModule)
+	pp.MustAddModule(appModule)
+	pp.MustAddPod(&podY3{T: t})
+	graph, err := pp.Graph()
+	if assert.NoError(t, err) {
+		var paths []string
+		for _, graphPod := range graph.Pods {
+			for _, exportEntry := range graphPod.ExportEntries {
+				paths = append(paths, graphPod.Name+": "+exportEntry.Path+" "+exportEntry.RefID)
+			}
+		}
+		assert.Equal(t, []string{
+			"postgres: postgres/depinj_test.podY1.Conn conn",
+			"postgres: postgres/depinj_test.podY2.DB db",
+			"app/cache: app/cache/depinj_test.podY1.Conn conn",
+		}, paths)
+	}
+	pp.MustSetUp(context.Background())
+	pp.MustTearDown()
+
+	pp = depinj.PodPool{}
+	pp.MustAddModule(newPostgresModule())
+	pp.MustAddPod(&podY4{})
+	err = pp.SetUp(context.Background())
+	assert.True(t, errors.Is(err, depinj.ErrBadImportEntry), "%v", err)
+	assert.Contains(t, err.Error(), `importEntryPath="depinj_test.podY4.Conn" refID="conn"`)
+
+	module := &depinj.Module{Name: "postgres"}
+	module.AddPod(&podY1{})
+	module.AddPod(&podY1{})
+	pp = depinj.PodPool{}
+	pp.MustAddModule(module)
+	err = pp.SetUp(context.Background())
+	assert.True(t, errors.Is(err, depinj.ErrBadExportEntry), "%v", err)
+	assert.Contains(t, err.Error(), `exportEntryPath="postgres/depinj_test.podY1.Conn"`)
+
+	module = &depinj.Module{Name: "postgres"}
+	module.AddPod(depinj.DummyPod{})
+	err = pp.AddModule(module)
+	assert.True(t, errors.Is(err, depinj.ErrInvalidPod), "%v", err)
+	assert.Contains(t, err.Error(), `modulePath="postgres"`)
+
+	module = &depinj.Module{Name: "postgres"}
+	module.AddModule(&depinj.Module{})
+	err = pp.AddModule(module)
+	assert.True(t, errors.Is(err, depinj.ErrInvalidModule), "%v", err)
+
+	module = &depinj.Module{Name: "postgres"}
+	module.AddModule(module)
+	err = pp.AddModule(module)
+	assert.True(t, errors.Is(err, depinj.ErrInvalidModule), "%v", err)
+}
+
+func TestPublishUnexported(t *testing.T) {
+	var pp depinj.PodPool
+	module := newPostgresModule()
+	module.Publish("dsn")
+	err := pp.AddModule(module)
+	assert.True(t, errors.Is(err, depinj.ErrInvalidModule), "%v", err)
+	assert.Contains(t, err.Error(), `published ref id not exported; modulePath="postgres" refID="dsn"`)
+
+	module = newPostgresModule()
+	module.PublishFieldTypes(reflect.TypeOf(""))
+	err = pp.AddModule(module)
+	assert.True(t, errors.Is(err, depinj.ErrInvalidModule), "%v", err)
+	assert.Contains(t, err.Error(), `published field type not exported; modulePath="postgres" fieldType="string"`)
+
+	appModule := &depinj.Module{Name: "app"}
+	appModule.AddModule(newPostgresModule())
+	appModule.Publish("db")
+	assert.NoError(t, pp.AddModule(appModule))
+
+	appModule = &depinj.Module{Name: "app"}
+	appModule.AddModule(newPostgresModule())
+	appModule.Publish("conn")
+	err = pp.AddModule(appModule)
+	assert.True(t, errors.Is(err, depinj.ErrInvalidModule), "%v", err)
+	assert.Contains(t, err.Error(), `modulePath="app" refID="conn"`)
+
+	pp = depinj.PodPool{}
+	module = &depinj.Module{Name: "postgres"}
+	module.AddPod(&podY5{Conn: "postgres"})
+	module.Publish("conn")
+	assert.NoError(t, pp.AddModule(module))
+	pp.AddRefLinkResolver(depinj.RefLinkMap{"conn": "conn"})
+	pp.MustAddPod(&podY4{})
+	assert.NoError(t, pp.SetUp(context.Background()))
+	pp.MustTearDown()
+}
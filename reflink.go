@@ -0,0 +1,116 @@
+package depinj
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// RefLinkResolver resolves ref links into ref ids.
+type RefLinkResolver interface {
+	// ResolveRefLink resolves the given ref link into a ref id.
+	// It returns false if the ref link is unresolvable.
+	ResolveRefLink(refLink string) (refID string, ok bool)
+}
+
+// RefLinkResolverFunc is the function type of RefLinkResolver.
+type RefLinkResolverFunc func(refLink string) (refID string, ok bool)
+
+var _ RefLinkResolver = RefLinkResolverFunc(nil)
+
+// ResolveRefLink implements RefLinkResolver.ResolveRefLink.
+func (rlrf RefLinkResolverFunc) ResolveRefLink(refLink string) (string, bool) { return rlrf(refLink) }
+
+// RefLinkMap is a RefLinkResolver which maps ref link names into ref ids.
+// The name of a ref link is the ref link without the leading `@`, e.g. the
+// name of ref link `@primary_db` is `primary_db`.
+type RefLinkMap map[string]string
+
+var _ RefLinkResolver = RefLinkMap(nil)
+
+// LoadRefLinkMap decodes a RefLinkMap from the given data with the given
+// unmarshal function, e.g. json.Unmarshal, yaml.Unmarshal or toml.Unmarshal.
+// The data is expected to be a mapping from ref link names to ref ids.
+func LoadRefLinkMap(data []byte, unmarshal func([]byte, interface{}) error) (RefLinkMap, error) {
+	var refLinkMap RefLinkMap
+
+	if err := unmarshal(data, &refLinkMap); err != nil {
+		return nil, fmt.Errorf("depinj: ref link map load failed: %w", err)
+	}
+
+	return refLinkMap, nil
+}
+
+// ReadRefLinkMapFile reads the file with the given name and decodes a
+// RefLinkMap from the content with the given unmarshal function.
+// See LoadRefLinkMap for details.
+func ReadRefLinkMapFile(fileName string, unmarshal func([]byte, interface{}) error) (RefLinkMap, error) {
+	data, err := ioutil.ReadFile(fileName)
+
+	if err != nil {
+		return nil, fmt.Errorf("depinj: ref link map read failed; fileName=%q: %w", fileName, err)
+	}
+
+	return LoadRefLinkMap(data, unmarshal)
+}
+
+// ResolveRefLink implements RefLinkResolver.ResolveRefLink.
+func (rlm RefLinkMap) ResolveRefLink(refLink string) (string, bool) {
+	refID, ok := rlm[refLinkName(refLink)]
+	return refID, ok
+}
+
+// DefaultEnvRefLinkPrefix is the default prefix of the environment variables
+// for EnvRefLinkResolver.
+const DefaultEnvRefLinkPrefix = "DEPINJ_LINK_"
+
+// EnvRefLinkResolver is a RefLinkResolver which resolves ref links from the
+// environment variables. The name of the environment variable for a ref link
+// is the prefix followed by the ref link name in upper case, with characters
+// other than letters and digits replaced with `_`, e.g. the environment variable
+// for ref link `@primary_db` is `DEPINJ_LINK_PRIMARY_DB` by default.
+type EnvRefLinkResolver struct {
+	// Prefix is the prefix of the environment variables,
+	// DefaultEnvRefLinkPrefix is used if it's empty.
+	Prefix string
+}
+
+var _ RefLinkResolver = EnvRefLinkResolver{}
+
+// ResolveRefLink implements RefLinkResolver.ResolveRefLink.
+func (erlr EnvRefLinkResolver) ResolveRefLink(refLink string) (string, bool) {
+	refID, ok := os.LookupEnv(erlr.EnvName(refLink))
+
+	if !ok || refID == "" {
+		return "", false
+	}
+
+	return refID, true
+}
+
+// EnvName returns the name of the environment variable for the given ref link.
+func (erlr EnvRefLinkResolver) EnvName(refLink string) string {
+	prefix := erlr.Prefix
+
+	if prefix == "" {
+		prefix = DefaultEnvRefLinkPrefix
+	}
+
+	return makeEnvName(prefix, refLinkName(refLink))
+}
+
+func refLinkName(refLink string) string {
+	return strings.TrimPrefix(refLink, "@")
+}
+
+func makeEnvName(prefix string, name string) string {
+	return prefix + strings.Map(func(r rune) rune {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			return unicode.ToUpper(r)
+		}
+
+		return '_'
+	}, name)
+}
@@ -0,0 +1,71 @@
+package depinj_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/depinj"
+)
+
+type podI1 struct {
+	depinj.DummyPod
+	DB1 string `export:"db_replica_1"`
+	DB2 string `export:"db_replica_2"`
+}
+
+func (p *podI1) SetUp(context.Context) error {
+	p.DB1, p.DB2 = "replica 1", "replica 2"
+	return nil
+}
+
+type podI2 struct {
+	depinj.DummyPod
+	PrimaryDB   string `import:"@primary_db"`
+	SecondaryDB string `import:"@secondary_db"`
+	T           *testing.T
+}
+
+func (p *podI2) SetUp(context.Context) error {
+	assert.Equal(p.T, "replica 2", p.PrimaryDB)
+	assert.Equal(p.T, "replica 1", p.SecondaryDB)
+	return nil
+}
+
+func TestRefLinkMapAndEnvRefLinkResolver(t *testing.T) {
+	refLinkMap, err := depinj.LoadRefLinkMap([]byte(`{"primary_db": "db_replica_1", "secondary_db": "db_replica_1"}`), json.Unmarshal)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, "DEPINJ_LINK_PRIMARY_DB", depinj.EnvRefLinkResolver{}.EnvName("@primary_db"))
+	os.Setenv("DEPINJ_LINK_PRIMARY_DB", "db_replica_2")
+	defer os.Unsetenv("DEPINJ_LINK_PRIMARY_DB")
+	var pp depinj.PodPool
+	pp.AddRefLinkResolver(depinj.EnvRefLinkResolver{})
+	pp.AddRefLinkResolver(refLinkMap)
+	for _, p := range []depinj.Pod{&podI1{}, &podI2{T: t}} {
+		err := pp.AddPod(p)
+		assert.NoError(t, err)
+	}
+	err = pp.SetUp(context.Background())
+	assert.NoError(t, err)
+	pp.TearDown()
+}
+
+func TestRefLinkMapUnresolvable(t *testing.T) {
+	_, err := depinj.LoadRefLinkMap([]byte(`[]`), json.Unmarshal)
+	assert.Error(t, err)
+	var pp depinj.PodPool
+	pp.AddRefLinkResolver(depinj.RefLinkMap{"primary_db": "db_replica_2"})
+	for _, p := range []depinj.Pod{&podI1{}, &podI2{T: t}} {
+		err := pp.AddPod(p)
+		assert.NoError(t, err)
+	}
+	err = pp.SetUp(context.Background())
+	assert.True(t, errors.Is(err, depinj.ErrBadImportEntry))
+	assert.EqualError(t, err, "depinj: bad import entry: unresolvable ref link; importEntryPath=\"depinj_test.podI2.SecondaryDB\" refLink=\"@secondary_db\"")
+}
@@ -0,0 +1,146 @@
+package depinj
+
+import (
+	"reflect"
+	"time"
+)
+
+// SetUpReport represents the timings of the last setup of a pool.
+type SetUpReport struct {
+	// Pods are the timings of the pods, in the order of setups.
+	Pods []PodSetUp `json:"pods"`
+
+	// CriticalPath is the indices in Pods of the pods on the critical path,
+	// i.e. the chain of dependencies with the longest total duration, which
+	// decides the duration of the setup even if the independent pods were set
+	// up in parallel.
+	CriticalPath []int `json:"criticalPath"`
+
+	// TotalDuration is the total duration of the pods, i.e. the duration of
+	// the sequential setup.
+	TotalDuration time.Duration `json:"totalDuration"`
+
+	// CriticalPathDuration is the total duration of the pods on the critical
+	// path, i.e. the estimated duration of a parallel setup.
+	CriticalPathDuration time.Duration `json:"criticalPathDuration"`
+}
+
+// ParallelSaving returns the estimated saving of a parallel setup, compared to
+// the sequential setup.
+func (sr *SetUpReport) ParallelSaving() time.Duration {
+	return sr.TotalDuration - sr.CriticalPathDuration
+}
+
+// PodSetUp represents the timings of the setup of a pod.
+type PodSetUp struct {
+	PodType string `json:"podType"`
+	PodName string `json:"podName,omitempty"`
+
+	// SetUpDuration is the duration of Pod.SetUp.
+	SetUpDuration time.Duration `json:"setUpDuration"`
+
+	// FilterDuration is the total duration of the filter methods run on the
+	// export entries of the pod.
+	FilterDuration time.Duration `json:"filterDuration"`
+}
+
+// Duration returns the duration of the setup of the pod, including the filter
+// methods run on the export entries of the pod.
+func (ps PodSetUp) Duration() time.Duration {
+	return ps.SetUpDuration + ps.FilterDuration
+}
+
+// SetUpReport returns the timings of the last setup of the pool, with the
+// critical path through the dependency graph. The pool must have been set up,
+// it fails with ErrPodPoolNotSetUp otherwise, with ErrPodPoolTornDown if the
+// pool has been torn down, or with ErrPodPoolBusy while the pool is busy.
+func (pp *PodPool) SetUpReport() (*SetUpReport, error) {
+	pp.mutex.Lock()
+	defer pp.mutex.Unlock()
+
+	if pp.isBusy {
+		return nil, ErrPodPoolBusy
+	}
+
+	switch pp.state {
+	case PodPoolStateSetUp:
+	case PodPoolStateTornDown:
+		return nil, ErrPodPoolTornDown
+	default:
+		return nil, ErrPodPoolNotSetUp
+	}
+
+	var report SetUpReport
+
+	for pod := pp.firstPod; pod != nil; pod = pod.Next {
+		podSetUp := PodSetUp{
+			PodType:       reflect.TypeOf(pod.Raw).String(),
+			PodName:       pod.FullName(),
+			SetUpDuration: pod.SetUpDuration,
+		}
+
+		for i := range pod.ExportEntries {
+			podSetUp.FilterDuration += pod.ExportEntries[i].FilterDuration
+		}
+
+		report.Pods = append(report.Pods, podSetUp)
+		report.TotalDuration += podSetUp.Duration()
+	}
+
+	report.CriticalPath, report.CriticalPathDuration = findCriticalPath(makeGraph(pp.firstPod), report.Pods)
+	return &report, nil
+}
+
+// findCriticalPath returns the path through the graph with the longest total
+// duration of the pods, and the total duration.
+func findCriticalPath(graph *Graph, podSetUps []PodSetUp) ([]int, time.Duration) {
+	if len(podSetUps) == 0 {
+		return nil, 0
+	}
+
+	inEdges := make([][]int, len(graph.Pods))
+
+	for i, edge := range graph.Edges {
+		inEdges[edge.To] = append(inEdges[edge.To], i)
+	}
+
+	// finishTimes[i] is the earliest time when the pod i can finish the setup
+	// in a parallel setup, and prevPods[i] is the pod which the pod i waits
+	// for last.
+	finishTimes := make([]time.Duration, len(graph.Pods))
+	prevPods := make([]int, len(graph.Pods))
+	lastPod := 0
+
+	// the pods are in the order of setups, so the depended pods go first.
+	for i := range graph.Pods {
+		prevPods[i] = -1
+
+		for _, j := range inEdges[i] {
+			if from := graph.Edges[j].From; prevPods[i] < 0 || finishTimes[from] > finishTimes[prevPods[i]] {
+				prevPods[i] = from
+			}
+		}
+
+		if prevPod := prevPods[i]; prevPod >= 0 {
+			finishTimes[i] = finishTimes[prevPod]
+		}
+
+		finishTimes[i] += podSetUps[i].Duration()
+
+		if finishTimes[i] > finishTimes[lastPod] {
+			lastPod = i
+		}
+	}
+
+	var criticalPath []int
+
+	for pod := lastPod; pod >= 0; pod = prevPods[pod] {
+		criticalPath = append(criticalPath, pod)
+	}
+
+	for i, j := 0, len(criticalPath)-1; i < j; i, j = i+1, j-1 {
+		criticalPath[i], criticalPath[j] = criticalPath[j], criticalPath[i]
+	}
+
+	return criticalPath, finishTimes[lastPod]
+}
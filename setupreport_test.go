@@ -0,0 +1,78 @@
+package depinj_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/depinj"
+)
+
+type podV1 struct {
+	depinj.DummyPod
+	Foo string `export:"foo"`
+}
+
+func (p *podV1) SetUp(context.Context) error { time.Sleep(30 * time.Millisecond); return nil }
+
+type podV2 struct {
+	depinj.DummyPod
+	Foo string `import:"foo"`
+}
+
+func (p *podV2) SetUp(context.Context) error { time.Sleep(30 * time.Millisecond); return nil }
+
+type podV3 struct {
+	depinj.DummyPod
+	Bar string `export:"bar"`
+}
+
+func (p *podV3) SetUp(context.Context) error { time.Sleep(10 * time.Millisecond); return nil }
+
+type podV4 struct {
+	depinj.DummyPod
+	Foo *string `filter:"foo,Slow,0"`
+}
+
+func (p *podV4) Slow(context.Context) error { time.Sleep(20 * time.Millisecond); return nil }
+
+func TestSetUpReport(t *testing.T) {
+	var pp depinj.PodPool
+	for _, p := range []depinj.Pod{&podV2{}, &podV3{}, &podV1{}, &podV4{}} {
+		pp.MustAddPod(p)
+	}
+
+	_, err := pp.SetUpReport()
+	assert.True(t, errors.Is(err, depinj.ErrPodPoolNotSetUp), "%v", err)
+
+	pp.MustSetUp(context.Background())
+	report, err := pp.SetUpReport()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	var podTypes []string
+	var totalDuration time.Duration
+	for _, podSetUp := range report.Pods {
+		podTypes = append(podTypes, podSetUp.PodType)
+		totalDuration += podSetUp.Duration()
+	}
+	assert.Equal(t, []string{"*depinj_test.podV4", "*depinj_test.podV1", "*depinj_test.podV2", "*depinj_test.podV3"}, podTypes)
+	assert.True(t, report.Pods[1].SetUpDuration >= 30*time.Millisecond, "%v", report.Pods[1].SetUpDuration)
+	assert.True(t, report.Pods[1].FilterDuration >= 20*time.Millisecond, "%v", report.Pods[1].FilterDuration)
+	assert.True(t, report.Pods[2].SetUpDuration >= 30*time.Millisecond, "%v", report.Pods[2].SetUpDuration)
+	assert.Equal(t, time.Duration(0), report.Pods[2].FilterDuration)
+	assert.True(t, report.Pods[3].SetUpDuration >= 10*time.Millisecond, "%v", report.Pods[3].SetUpDuration)
+	assert.Equal(t, totalDuration, report.TotalDuration)
+
+	assert.Equal(t, []int{0, 1, 2}, report.CriticalPath)
+	assert.Equal(t, report.Pods[0].Duration()+report.Pods[1].Duration()+report.Pods[2].Duration(), report.CriticalPathDuration)
+	assert.Equal(t, report.Pods[3].Duration(), report.ParallelSaving())
+
+	pp.MustTearDown()
+	_, err = pp.SetUpReport()
+	assert.True(t, errors.Is(err, depinj.ErrPodPoolTornDown), "%v", err)
+}
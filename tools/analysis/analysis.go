@@ -0,0 +1,135 @@
+// Package analysis provides the analyzer which checks the pods at build time,
+// with the same rules and errors as depinj.PodPool.AddPod, e.g. malformed
+// tags, unexported tagged fields, filter fields which aren't pointers, missing
+// or mistyped filter methods and non-integer priorities. The analyzer is
+// compatible with `go vet`, see cmd/depinj-vet.
+//
+// Given the pods added to a pool, by the flag `-pods` or `-registration`, the
+// analyzer also resolves the pods across packages, as depinj.PodPool.SetUp
+// does, and reports the error, e.g. unmatched ref ids, mismatched field types,
+// duplicate exporters or circular dependencies, at the offending struct field.
+//
+// The flag `-pods` is the comma-separated list of the pod types qualified with
+// import paths, e.g. `example.com/app.Server,example.com/app/db.DB`, in the
+// order of depinj.PodPool.AddPod, which are resolved when the package of the
+// first pod type is analyzed.
+//
+// The flag `-registration` is the comma-separated list of the registration
+// functions qualified with import paths, e.g. `example.com/app.addPods`. The
+// pods added by the calls to depinj.PodPool.AddPod and
+// depinj.PodPool.MustAddPod in a registration function are resolved, in the
+// order of the calls, when the package of the function is analyzed. The ref
+// links are resolved with the composite literals of depinj.RefLinkMap passed
+// to depinj.PodPool.AddRefLinkResolver in the function, and the JSON file
+// given by the flag `-reflinks`, since other ref link resolvers, including
+// Pod.ResolveRefLink, can't be called statically. The calls to
+// depinj.PodPool.AddRootPod, depinj.PodPool.AddNamedPod,
+// depinj.PodPool.AddModule and their Must* forms are reported as unsupported,
+// since the root pods, the names and the module scopes of pods aren't modeled
+// statically.
+package analysis
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+
+	"github.com/roy2220/depinj/tools/internal/static"
+)
+
+// Analyzer is the analyzer which checks the pods. A pod is a named structure
+// with the entries tagged by `import`, `export`, `filter` or `config`, whose
+// pointer implements depinj.Pod.
+var Analyzer = &analysis.Analyzer{
+	Name: "depinj",
+	Doc:  "check the pods of depinj",
+	Run:  run,
+}
+
+var flags struct {
+	PodTypeNames          string
+	RegistrationFuncNames string
+	RefLinkMapFileName    string
+}
+
+func init() {
+	Analyzer.Flags.StringVar(&flags.PodTypeNames, "pods", "", "the comma-separated list of the pod types to resolve")
+	Analyzer.Flags.StringVar(&flags.RegistrationFuncNames, "registration", "", "the comma-separated list of the registration functions whose pods to resolve")
+	Analyzer.Flags.StringVar(&flags.RefLinkMapFileName, "reflinks", "", "the name of the JSON file mapping ref link names to ref ids")
+}
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	reportedErrs := make(map[reportedErr]struct{})
+
+	for _, name := range pass.Pkg.Scope().Names() {
+		typeName, ok := pass.Pkg.Scope().Lookup(name).(*types.TypeName)
+
+		if !ok || typeName.IsAlias() || !isPod(typeName.Type()) {
+			continue
+		}
+
+		for _, err := range static.CheckPod(types.NewPointer(typeName.Type())) {
+			pos := typeName.Pos()
+
+			if staticErr, ok := err.(*static.Error); ok && isInFiles(pass.Files, staticErr.Pos) {
+				pos = staticErr.Pos
+			}
+
+			reportedErr := reportedErr{pos, err.Error()}
+
+			if _, ok := reportedErrs[reportedErr]; ok {
+				continue
+			}
+
+			reportedErrs[reportedErr] = struct{}{}
+			pass.Reportf(pos, "%v", err)
+		}
+	}
+
+	podGraphs, err := findPodGraphs(pass)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, podGraph := range podGraphs {
+		if err := podGraph.Check(); err != nil {
+			if _, ok := reportedErrs[reportedErr{err.Pos, err.Error()}]; !ok {
+				pass.Reportf(err.Pos, "%v", err)
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+type reportedErr struct {
+	Pos     token.Pos
+	Message string
+}
+
+func isPod(typ types.Type) bool {
+	if _, ok := typ.Underlying().(*types.Struct); !ok {
+		return false
+	}
+
+	methodSet := types.NewMethodSet(types.NewPointer(typ))
+
+	if methodSet.Lookup(nil, "SetUp") == nil || methodSet.Lookup(nil, "TearDown") == nil {
+		return false
+	}
+
+	return static.HasEntry(typ.Underlying().(*types.Struct))
+}
+
+func isInFiles(files []*ast.File, pos token.Pos) bool {
+	for _, file := range files {
+		if pos >= file.Pos() && pos <= file.End() {
+			return true
+		}
+	}
+
+	return false
+}
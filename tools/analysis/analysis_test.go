@@ -0,0 +1,147 @@
+package analysis_test
+
+import (
+	"go/ast"
+	"go/token"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	goanalysis "golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/packages"
+
+	"github.com/roy2220/depinj/tools/analysis"
+)
+
+func TestAnalyzer(t *testing.T) {
+	runAnalyzer(t, "github.com/roy2220/depinj/tools/analysis/testdata/a")
+}
+
+func TestAnalyzerWithRegistration(t *testing.T) {
+	const pkgPath = "github.com/roy2220/depinj/tools/analysis/testdata/b"
+	setFlag(t, "registration", strings.Join([]string{
+		pkgPath + ".addPods",
+		pkgPath + ".addPodsWithDuplicateRefID",
+		pkgPath + ".addPodsWithFieldTypeMismatch",
+		pkgPath + ".addPodsWithUnresolvedRefLink",
+		pkgPath + ".addPodsWithCircularDependency",
+		pkgPath + ".addPodsOfInterfaceType",
+		pkgPath + ".addPodsWithNames",
+		pkgPath + ".addPodsAsRoots",
+		pkgPath + ".addModules",
+		pkgPath + ".setUp",
+	}, ","))
+	runAnalyzer(t, pkgPath, pkgPath+"/c")
+}
+
+func TestAnalyzerWithPods(t *testing.T) {
+	const pkgPath = "github.com/roy2220/depinj/tools/analysis/testdata/d"
+	setFlag(t, "pods", strings.Join([]string{
+		pkgPath + ".Qux",
+		"github.com/roy2220/depinj/tools/analysis/testdata/b/c.Foo",
+		"github.com/roy2220/depinj/tools/analysis/testdata/b/c.Bar",
+	}, ","))
+	runAnalyzer(t, pkgPath)
+}
+
+func setFlag(t *testing.T, name string, value string) {
+	if err := analysis.Analyzer.Flags.Set(name, value); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { analysis.Analyzer.Flags.Set(name, "") })
+}
+
+// runAnalyzer runs the analyzer on the given package and checks the
+// diagnostics against the `// want` comments in the given package and the
+// other given packages imported, as analysistest.Run does.
+func runAnalyzer(t *testing.T, pkgPath string, otherPkgPaths ...string) {
+	pkgs, err := packages.Load(&packages.Config{Mode: packages.LoadAllSyntax, Tests: false}, pkgPath)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if packages.PrintErrors(pkgs) >= 1 {
+		t.FailNow()
+	}
+
+	pkg := pkgs[0]
+	var diagnostics []goanalysis.Diagnostic
+	pass := goanalysis.Pass{
+		Analyzer:  analysis.Analyzer,
+		Fset:      pkg.Fset,
+		Files:     pkg.Syntax,
+		Pkg:       pkg.Types,
+		TypesInfo: pkg.TypesInfo,
+		Report:    func(diagnostic goanalysis.Diagnostic) { diagnostics = append(diagnostics, diagnostic) },
+	}
+
+	if _, err := analysis.Analyzer.Run(&pass); err != nil {
+		t.Fatal(err)
+	}
+
+	wants := parseWants(t, pkg.Fset, pkg.Syntax)
+
+	for _, otherPkgPath := range otherPkgPaths {
+		for key, want := range parseWants(t, pkg.Fset, lookUpImport(pkg, otherPkgPath).Syntax) {
+			wants[key] = want
+		}
+	}
+
+	for _, diagnostic := range diagnostics {
+		position := pkg.Fset.Position(diagnostic.Pos)
+		key := position.Filename + ":" + strconv.Itoa(position.Line)
+		want, ok := wants[key]
+
+		if !assert.True(t, ok, "unexpected diagnostic at %v: %v", position, diagnostic.Message) {
+			continue
+		}
+
+		assert.Regexp(t, want, diagnostic.Message, "at %v", position)
+		delete(wants, key)
+	}
+
+	for key, want := range wants {
+		t.Errorf("missing diagnostic at %v: %v", key, want)
+	}
+}
+
+func lookUpImport(pkg *packages.Package, pkgPath string) *packages.Package {
+	if pkg.PkgPath == pkgPath {
+		return pkg
+	}
+
+	for _, importedPkg := range pkg.Imports {
+		if pkg := lookUpImport(importedPkg, pkgPath); pkg != nil {
+			return pkg
+		}
+	}
+
+	return nil
+}
+
+var wantPattern = regexp.MustCompile("^// want `(.*)`$")
+
+func parseWants(t *testing.T, fileSet *token.FileSet, files []*ast.File) map[string]*regexp.Regexp {
+	wants := make(map[string]*regexp.Regexp)
+
+	for _, file := range files {
+		for _, commentGroup := range file.Comments {
+			for _, comment := range commentGroup.List {
+				match := wantPattern.FindStringSubmatch(strings.TrimSpace(comment.Text))
+
+				if match == nil {
+					continue
+				}
+
+				position := fileSet.Position(comment.Pos())
+				wants[position.Filename+":"+strconv.Itoa(position.Line)] = regexp.MustCompile(match[1])
+			}
+		}
+	}
+
+	return wants
+}
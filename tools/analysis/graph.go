@@ -0,0 +1,330 @@
+package analysis
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/ast/astutil"
+
+	"github.com/roy2220/depinj"
+	"github.com/roy2220/depinj/tools/internal/static"
+)
+
+// podGraph represents the pods checked together, in the order of
+// depinj.PodPool.AddPod. Err is set if the pods can't be checked statically,
+// e.g. some are added by the unsupported methods of depinj.PodPool.
+type podGraph struct {
+	Pos             token.Pos
+	PodTypes        []types.Type
+	PodPositions    []token.Pos
+	RefLinkResolver depinj.RefLinkResolver
+	Err             *static.Error
+}
+
+// findPodGraphs returns the pod graphs to check in the package being analyzed,
+// which are given by the flag `-pods` and `-registration`.
+func findPodGraphs(pass *analysis.Pass) ([]*podGraph, error) {
+	var podGraphs []*podGraph
+
+	if podTypeNames := splitList(flags.PodTypeNames); len(podTypeNames) >= 1 {
+		if pkgPath, _ := splitQualifiedName(podTypeNames[0]); pkgPath == pass.Pkg.Path() {
+			podGraph, err := makePodGraphByPodTypeNames(pass, podTypeNames)
+
+			if err != nil {
+				return nil, err
+			}
+
+			podGraphs = append(podGraphs, podGraph)
+		}
+	}
+
+	for _, funcName := range splitList(flags.RegistrationFuncNames) {
+		if pkgPath, name := splitQualifiedName(funcName); pkgPath == pass.Pkg.Path() {
+			podGraph, err := makePodGraphByRegistrationFunc(pass, name)
+
+			if err != nil {
+				return nil, err
+			}
+
+			podGraphs = append(podGraphs, podGraph)
+		}
+	}
+
+	if len(podGraphs) == 0 || flags.RefLinkMapFileName == "" {
+		return podGraphs, nil
+	}
+
+	refLinkMap, err := depinj.ReadRefLinkMapFile(flags.RefLinkMapFileName, json.Unmarshal)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, podGraph := range podGraphs {
+		podGraph.RefLinkResolver = mergeRefLinkResolvers(podGraph.RefLinkResolver, refLinkMap)
+	}
+
+	return podGraphs, nil
+}
+
+func makePodGraphByPodTypeNames(pass *analysis.Pass, podTypeNames []string) (*podGraph, error) {
+	var podGraph podGraph
+
+	for _, podTypeName := range podTypeNames {
+		pkgPath, name := splitQualifiedName(podTypeName)
+		pkg := lookUpPackage(pass.Pkg, pkgPath, make(map[*types.Package]struct{}))
+
+		if pkg == nil {
+			return nil, fmt.Errorf("package not found; podTypeName=%q", podTypeName)
+		}
+
+		typeName, ok := pkg.Scope().Lookup(name).(*types.TypeName)
+
+		if !ok {
+			return nil, fmt.Errorf("type not found; podTypeName=%q", podTypeName)
+		}
+
+		if len(podGraph.PodTypes) == 0 {
+			podGraph.Pos = typeName.Pos()
+		}
+
+		podGraph.PodTypes = append(podGraph.PodTypes, types.NewPointer(typeName.Type()))
+		podGraph.PodPositions = append(podGraph.PodPositions, typeName.Pos())
+	}
+
+	return &podGraph, nil
+}
+
+func makePodGraphByRegistrationFunc(pass *analysis.Pass, funcName string) (*podGraph, error) {
+	funcDecl := lookUpFuncDecl(pass.Files, funcName)
+
+	if funcDecl == nil {
+		return nil, fmt.Errorf("registration function not found; funcName=%q", pass.Pkg.Path()+"."+funcName)
+	}
+
+	podGraph := podGraph{Pos: funcDecl.Name.Pos()}
+	var refLinkMap depinj.RefLinkMap
+
+	ast.Inspect(funcDecl.Body, func(node ast.Node) bool {
+		callExpr, ok := node.(*ast.CallExpr)
+
+		if !ok {
+			return true
+		}
+
+		switch methodName := podPoolMethodName(pass.TypesInfo, callExpr); methodName {
+		case "AddRootPod", "MustAddRootPod", "AddNamedPod", "MustAddNamedPod", "AddModule", "MustAddModule":
+			// the root pods, the names and the module scopes of pods aren't
+			// modeled statically.
+			if podGraph.Err == nil {
+				podGraph.Err = &static.Error{
+					Pos: callExpr.Pos(),
+					Err: fmt.Errorf("%w: pod pool method; methodName=%q", static.ErrUnsupported, methodName),
+				}
+			}
+		case "AddPod", "MustAddPod":
+			podGraph.PodTypes = append(podGraph.PodTypes, pass.TypesInfo.TypeOf(callExpr.Args[0]))
+			podGraph.PodPositions = append(podGraph.PodPositions, callExpr.Args[0].Pos())
+		case "AddRefLinkResolver":
+			refLinkMap = evaluateRefLinkMap(pass.TypesInfo, callExpr.Args[0], refLinkMap)
+		}
+
+		return true
+	})
+
+	if refLinkMap != nil {
+		podGraph.RefLinkResolver = refLinkMap
+	}
+
+	return &podGraph, nil
+}
+
+// Check resolves the pods in the graph, as depinj.PodPool.SetUp does, and
+// returns the error with the position of the offending struct field.
+func (pg *podGraph) Check() *static.Error {
+	if pg.Err != nil {
+		return pg.Err
+	}
+
+	var pods []*static.Pod
+
+	for i, podType := range pg.PodTypes {
+		if types.IsInterface(podType) {
+			return &static.Error{
+				Pos: pg.PodPositions[i],
+				Err: fmt.Errorf("%w: pod type unknown; podType=%q", static.ErrUnsupported, podType),
+			}
+		}
+
+		pod, err := static.ParsePod(podType)
+
+		if err != nil {
+			staticErr := err.(*static.Error)
+
+			if !staticErr.Pos.IsValid() {
+				staticErr.Pos = pg.PodPositions[i]
+			}
+
+			return staticErr
+		}
+
+		pods = append(pods, pod)
+	}
+
+	_, err := static.Resolve(pods, pg.RefLinkResolver)
+
+	if err == nil {
+		return nil
+	}
+
+	staticErr := err.(*static.Error)
+
+	if !staticErr.Pos.IsValid() {
+		staticErr.Pos = pg.Pos
+	}
+
+	return staticErr
+}
+
+func podPoolMethodName(typesInfo *types.Info, callExpr *ast.CallExpr) string {
+	selectorExpr, ok := callExpr.Fun.(*ast.SelectorExpr)
+
+	if !ok {
+		return ""
+	}
+
+	selection, ok := typesInfo.Selections[selectorExpr]
+
+	if !ok || selection.Kind() != types.MethodVal {
+		return ""
+	}
+
+	recvType := selection.Recv()
+
+	if pointer, ok := recvType.(*types.Pointer); ok {
+		recvType = pointer.Elem()
+	}
+
+	if !isDepinjType(recvType, "PodPool") {
+		return ""
+	}
+
+	return selection.Obj().Name()
+}
+
+// evaluateRefLinkMap adds the ref links of the given expression, if it's a
+// composite literal of depinj.RefLinkMap with constant keys and values, to the
+// given map, since other ref link resolvers can't be evaluated statically.
+func evaluateRefLinkMap(typesInfo *types.Info, expr ast.Expr, refLinkMap depinj.RefLinkMap) depinj.RefLinkMap {
+	compositeLit, ok := astutil.Unparen(expr).(*ast.CompositeLit)
+
+	if !ok || !isDepinjType(typesInfo.TypeOf(compositeLit), "RefLinkMap") {
+		return refLinkMap
+	}
+
+	for _, elt := range compositeLit.Elts {
+		keyValueExpr, ok := elt.(*ast.KeyValueExpr)
+
+		if !ok {
+			continue
+		}
+
+		key := typesInfo.Types[keyValueExpr.Key].Value
+		value := typesInfo.Types[keyValueExpr.Value].Value
+
+		if key == nil || key.Kind() != constant.String || value == nil || value.Kind() != constant.String {
+			continue
+		}
+
+		if refLinkMap == nil {
+			refLinkMap = make(depinj.RefLinkMap)
+		}
+
+		refLinkMap[constant.StringVal(key)] = constant.StringVal(value)
+	}
+
+	return refLinkMap
+}
+
+func mergeRefLinkResolvers(refLinkResolver1 depinj.RefLinkResolver, refLinkResolver2 depinj.RefLinkResolver) depinj.RefLinkResolver {
+	if refLinkResolver1 == nil {
+		return refLinkResolver2
+	}
+
+	return depinj.RefLinkResolverFunc(func(refLink string) (string, bool) {
+		if refID, ok := refLinkResolver1.ResolveRefLink(refLink); ok {
+			return refID, true
+		}
+
+		return refLinkResolver2.ResolveRefLink(refLink)
+	})
+}
+
+func lookUpFuncDecl(files []*ast.File, funcName string) *ast.FuncDecl {
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			if funcDecl, ok := decl.(*ast.FuncDecl); ok && funcDecl.Recv == nil && funcDecl.Name.Name == funcName && funcDecl.Body != nil {
+				return funcDecl
+			}
+		}
+	}
+
+	return nil
+}
+
+func lookUpPackage(pkg *types.Package, pkgPath string, visitedPkgs map[*types.Package]struct{}) *types.Package {
+	if pkg.Path() == pkgPath {
+		return pkg
+	}
+
+	if _, ok := visitedPkgs[pkg]; ok {
+		return nil
+	}
+
+	visitedPkgs[pkg] = struct{}{}
+
+	for _, importedPkg := range pkg.Imports() {
+		if pkg := lookUpPackage(importedPkg, pkgPath, visitedPkgs); pkg != nil {
+			return pkg
+		}
+	}
+
+	return nil
+}
+
+func isDepinjType(typ types.Type, name string) bool {
+	named, ok := typ.(*types.Named)
+
+	if !ok {
+		return false
+	}
+
+	typeName := named.Obj()
+	return typeName.Pkg() != nil && typeName.Pkg().Path() == depinjPkgPath && typeName.Name() == name
+}
+
+const depinjPkgPath = "github.com/roy2220/depinj"
+
+func splitList(str string) []string {
+	if str == "" {
+		return nil
+	}
+
+	return strings.Split(str, ",")
+}
+
+func splitQualifiedName(qualifiedName string) (string, string) {
+	i := strings.LastIndexByte(qualifiedName, '.')
+
+	if i < 0 {
+		return "", qualifiedName
+	}
+
+	return qualifiedName[:i], qualifiedName[i+1:]
+}
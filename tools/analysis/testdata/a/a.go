@@ -0,0 +1,61 @@
+// Package a provides the pods for the tests of package analysis.
+package a
+
+import (
+	"context"
+
+	"github.com/roy2220/depinj"
+)
+
+type Good struct {
+	depinj.DummyPod
+	Foo    int    `import:"Foo"`
+	Bar    string `export:"Bar,labels=kind:bar"`
+	Filter *int   `filter:"Foo,ModifyFoo,0,id=good"`
+	Port   int    `config:"port,default=8080"`
+	GoodFilters
+}
+
+func (Good) ModifyFoo(context.Context) error { return nil }
+
+type GoodFilters struct {
+	Bar *string `filter:"*,ModifyBar,-1"`
+}
+
+func (*GoodFilters) ModifyBar(_ context.Context, _ depinj.FilterTarget, bar string) (string, error) {
+	return bar, nil
+}
+
+type Bad struct {
+	depinj.DummyPod
+	foo    int     `export:"Foo"`                // want `depinj: bad export entry: field unexported; exportEntryPath="a.Bad.foo"`
+	Bar    int     `filter:"Bar,ModifyBar,0"`    // want `depinj: bad filter entry: non-pointer field type; .*`
+	Baz    *int    `filter:"Baz,ModifyBazz,0"`   // want `depinj: bad filter entry: method undefined or unexported; .*`
+	Qux    *string `filter:"Qux,ModifyQux,0"`    // want `depinj: bad filter entry: function type mismatch .*`
+	Quux   *int    `filter:"Quux,ModifyBaz"`     // want `depinj: bad filter entry: missing argument .priority.; .*`
+	Corge  *int    `filter:"Corge,ModifyBaz,hi"` // want `depinj: bad filter entry: priority parse failed; .*`
+	Grault int     `import:"Grault,select=x"`    // want `depinj: bad import entry: selector parse failed; .*`
+	Garply int     `export:"Garply,labels=x"`    // want `depinj: bad export entry: .*`
+	BadFilters
+}
+
+func (Bad) ModifyBaz(context.Context) error { return nil }
+
+func (Bad) ModifyQux(_ context.Context, qux int) (int, error) { return qux, nil }
+
+type BadFilters struct {
+	Fred *int `filter:"Fred,ModifyFred,0"` // want `depinj: bad filter entry: method undefined or unexported; .*`
+}
+
+// NotPod has no SetUp method, so it isn't checked.
+type NotPod struct {
+	foo int `export:"Foo"`
+}
+
+type BadInline struct {
+	depinj.DummyPod
+	Deps struct {
+		foo int `import:"Foo"` // want `depinj: bad import entry: field unexported; importEntryPath="a.BadInline.Deps.foo"`
+	} `depinj:"inline"`
+	Bar int `depinj:"flatten"` // want `depinj: invalid pod: unknown depinj tag; .*`
+}
@@ -0,0 +1,102 @@
+// Package b provides the pods and the registration functions for the tests of
+// package analysis.
+package b
+
+import (
+	"context"
+
+	"github.com/roy2220/depinj"
+	"github.com/roy2220/depinj/tools/analysis/testdata/b/c"
+)
+
+type Qux struct {
+	depinj.DummyPod
+	Bar string `import:"Bar"`
+	Baz int    `export:"Baz"`
+}
+
+type Quux struct {
+	depinj.DummyPod
+	Bar string `import:"@bar"` // want `depinj: bad import entry: .*importEntryPath="b.Quux.Bar"`
+}
+
+type Grault struct {
+	depinj.DummyPod
+	Foo int `import:"Fooo"` // want `depinj: bad import entry: .*importEntryPath="b.Grault.Foo"`
+}
+
+type Garply struct {
+	depinj.DummyPod
+	Waldo int `import:"Waldo"`
+	Foo   int `export:"Foo"`
+}
+
+type Waldo struct {
+	depinj.DummyPod
+	Foo   int `import:"Foo"` // want `depinj: pod circular dependency; .*`
+	Waldo int `export:"Waldo"`
+}
+
+func addPods(pp *depinj.PodPool) {
+	pp.MustAddPod(&c.Foo{})
+	pp.MustAddPod(&c.Bar{})
+	pp.MustAddPod(&Qux{})
+	pp.AddRefLinkResolver(depinj.RefLinkMap{"bar": "Bar"})
+	pp.MustAddPod(&Quux{})
+}
+
+func addPodsWithDuplicateRefID(pp *depinj.PodPool) {
+	pp.MustAddPod(&c.Foo{})
+	pp.MustAddPod(&c.Foo2{})
+}
+
+func addPodsWithFieldTypeMismatch(pp *depinj.PodPool) {
+	pp.MustAddPod(&c.Baz{})
+	pp.MustAddPod(&Qux{})
+	pp.MustAddPod(&c.Bar{})
+	pp.MustAddPod(&c.Foo{})
+}
+
+func addPodsWithUnresolvedRefLink(pp *depinj.PodPool) error {
+	if err := pp.AddPod(&c.Bar{}); err != nil {
+		return err
+	}
+
+	pp.MustAddPod(&c.Foo{})
+	return pp.AddPod(&Quux{})
+}
+
+func addPodsWithCircularDependency(pp *depinj.PodPool) {
+	pp.MustAddPod(&Garply{})
+	pp.MustAddPod(&Waldo{})
+}
+
+func addPodsOfInterfaceType(pp *depinj.PodPool, pods []depinj.Pod) {
+	pp.MustAddPod(&Grault{})
+
+	for _, pod := range pods {
+		pp.MustAddPod(pod) // want `depinj: unsupported statically: .*`
+	}
+}
+
+func addPodsWithNames(pp *depinj.PodPool) {
+	pp.MustAddPod(&c.Foo{})
+	pp.MustAddNamedPod("primary", &c.Bar{}) // want `depinj: unsupported statically: pod pool method; methodName="MustAddNamedPod"`
+	pp.MustAddRootPod(&Qux{})
+}
+
+func addPodsAsRoots(pp *depinj.PodPool) error {
+	return pp.AddRootPod(&Grault{}) // want `depinj: unsupported statically: pod pool method; methodName="AddRootPod"`
+}
+
+func addModules(pp *depinj.PodPool) {
+	module := &depinj.Module{Name: "c"}
+	module.AddPod(&c.Foo{})
+	pp.MustAddModule(module) // want `depinj: unsupported statically: pod pool method; methodName="MustAddModule"`
+}
+
+func setUp(ctx context.Context) {
+	var pp depinj.PodPool
+	pp.MustAddPod(&Grault{})
+	pp.MustSetUp(ctx)
+}
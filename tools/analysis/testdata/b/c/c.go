@@ -0,0 +1,28 @@
+// Package c provides the pods for the tests of package analysis, which are
+// resolved with the pods of package b.
+package c
+
+import (
+	"github.com/roy2220/depinj"
+)
+
+type Foo struct {
+	depinj.DummyPod
+	Foo int `export:"Foo"`
+}
+
+type Bar struct {
+	depinj.DummyPod
+	Foo int    `import:"Foo"`
+	Bar string `export:"Bar"`
+}
+
+type Foo2 struct {
+	depinj.DummyPod
+	Foo int `export:"Foo"` // want `depinj: bad export entry: duplicate ref id; .*`
+}
+
+type Baz struct {
+	depinj.DummyPod
+	Baz int64 `import:"Baz"` // want `depinj: bad import entry: field type mismatch; .*`
+}
@@ -0,0 +1,13 @@
+// Package d provides the pods for the tests of package analysis, which are
+// resolved by the flag `-pods`.
+package d
+
+import (
+	"github.com/roy2220/depinj"
+	_ "github.com/roy2220/depinj/tools/analysis/testdata/b/c"
+)
+
+type Qux struct {
+	depinj.DummyPod
+	Bar int `import:"Bar"` // want `depinj: bad import entry: field type mismatch; .*`
+}
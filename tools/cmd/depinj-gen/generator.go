@@ -0,0 +1,531 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/format"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/tools/go/packages"
+
+	"github.com/roy2220/depinj"
+	"github.com/roy2220/depinj/tools/internal/static"
+)
+
+type options struct {
+	OutputFileName     string
+	FuncName           string
+	RefLinkMapFileName string
+	PodTypeNames       []string
+}
+
+func generate(options options, unmarshal func([]byte, interface{}) error) ([]byte, error) {
+	outputFileName, err := filepath.Abs(options.OutputFileName)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var refLinkResolver depinj.RefLinkResolver
+
+	if options.RefLinkMapFileName != "" {
+		refLinkMap, err := depinj.ReadRefLinkMapFile(options.RefLinkMapFileName, unmarshal)
+
+		if err != nil {
+			return nil, err
+		}
+
+		refLinkResolver = refLinkMap
+	}
+
+	loader := loader{OutputFileName: outputFileName}
+	pods, err := loader.LoadPods(options.PodTypeNames)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, pod := range pods {
+		if len(pod.ConfigEntries) >= 1 {
+			configEntry := pod.ConfigEntries[0]
+			return nil, loader.Error(&static.Error{
+				Pos: configEntry.Pos(),
+				Err: fmt.Errorf("%w: config entry; configEntryPath=%q", static.ErrUnsupported, configEntry.Path),
+			})
+		}
+
+		if entry := findEntryBehindPointer(pod); entry != nil {
+			return nil, loader.Error(&static.Error{
+				Pos: entry.Pos(),
+				Err: fmt.Errorf("%w: entry behind pointer field; entryPath=%q", static.ErrUnsupported, entry.Path),
+			})
+		}
+	}
+
+	sortedPods, err := static.Resolve(pods, refLinkResolver)
+
+	if err != nil {
+		return nil, loader.Error(err)
+	}
+
+	generator := generator{
+		Package:  loader.OutputPackage,
+		FuncName: options.FuncName,
+	}
+
+	return generator.Generate(pods, sortedPods)
+}
+
+// findEntryBehindPointer returns the first entry of the given pod reached
+// through an embedded or inline pointer field, which may be nil until
+// depinj.PodPool.AddPod sets it to a new structure, or nil if none.
+func findEntryBehindPointer(pod *static.Pod) *static.Entry {
+	var entries []*static.Entry
+
+	for _, importEntry := range pod.ImportEntries {
+		entries = append(entries, &importEntry.Entry)
+	}
+
+	for _, exportEntry := range pod.ExportEntries {
+		entries = append(entries, &exportEntry.Entry)
+	}
+
+	for _, filterEntry := range pod.FilterEntries {
+		entries = append(entries, &filterEntry.Entry)
+	}
+
+	for _, entry := range entries {
+		for _, field := range entry.FieldPath[:len(entry.FieldPath)-1] {
+			if _, ok := field.Type().(*types.Pointer); ok {
+				return entry
+			}
+		}
+	}
+
+	return nil
+}
+
+type loader struct {
+	OutputFileName string
+
+	fileSet       *token.FileSet
+	OutputPackage *types.Package
+}
+
+func (l *loader) LoadPods(podTypeNames []string) ([]*static.Pod, error) {
+	patterns := []string{"."}
+
+	for _, podTypeName := range podTypeNames {
+		if i := strings.LastIndexByte(podTypeName, '.'); i >= 0 {
+			patterns = append(patterns, podTypeName[:i])
+		}
+	}
+
+	pkgs, err := packages.Load(&packages.Config{
+		Mode: packages.LoadAllSyntax,
+		Dir:  filepath.Dir(l.OutputFileName),
+	}, patterns...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := l.checkPackages(pkgs); err != nil {
+		return nil, err
+	}
+
+	l.fileSet = pkgs[0].Fset
+	path2Package := make(map[string]*types.Package)
+
+	for _, pkg := range pkgs {
+		path2Package[pkg.PkgPath] = pkg.Types
+
+		for _, fileName := range pkg.GoFiles {
+			if fileName == l.OutputFileName || filepath.Dir(fileName) == filepath.Dir(l.OutputFileName) {
+				l.OutputPackage = pkg.Types
+			}
+		}
+	}
+
+	if l.OutputPackage == nil {
+		return nil, fmt.Errorf("no package found in directory %q", filepath.Dir(l.OutputFileName))
+	}
+
+	var pods []*static.Pod
+
+	for _, podTypeName := range podTypeNames {
+		pkg, name := l.OutputPackage, podTypeName
+
+		if i := strings.LastIndexByte(podTypeName, '.'); i >= 0 {
+			pkg, name = path2Package[podTypeName[:i]], podTypeName[i+1:]
+
+			if pkg == nil {
+				return nil, fmt.Errorf("package not found; podTypeName=%q", podTypeName)
+			}
+		}
+
+		typeName, ok := pkg.Scope().Lookup(name).(*types.TypeName)
+
+		if !ok {
+			return nil, fmt.Errorf("type not found; podTypeName=%q", podTypeName)
+		}
+
+		pod, err := static.ParsePod(types.NewPointer(typeName.Type()))
+
+		if err != nil {
+			return nil, l.Error(err)
+		}
+
+		pods = append(pods, pod)
+	}
+
+	return pods, nil
+}
+
+// Error prefixes the given error with the position of the offending field or
+// pod structure if any.
+func (l *loader) Error(err error) error {
+	var staticErr *static.Error
+
+	if !errors.As(err, &staticErr) || !staticErr.Pos.IsValid() {
+		return err
+	}
+
+	return fmt.Errorf("%v: %w", l.fileSet.Position(staticErr.Pos), err)
+}
+
+func (l *loader) checkPackages(pkgs []*packages.Package) error {
+	var errs []string
+
+	outputDirName := filepath.Dir(l.OutputFileName)
+
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		for _, err := range pkg.Errors {
+			// the output file may be out of date or missing, so the type
+			// errors of the output package are tolerated.
+			if err.Kind == packages.TypeError && strings.HasPrefix(err.Pos, outputDirName+string(filepath.Separator)) {
+				continue
+			}
+
+			errs = append(errs, err.Error())
+		}
+	})
+
+	if len(errs) >= 1 {
+		return fmt.Errorf("package load failed:\n\t%s", strings.Join(errs, "\n\t"))
+	}
+
+	return nil
+}
+
+type generator struct {
+	Package  *types.Package
+	FuncName string
+
+	buffer      bytes.Buffer
+	importPaths map[string]importInfo
+	usedNames   map[string]struct{}
+	pod2Name    map[*static.Pod]string
+}
+
+func (g *generator) Generate(pods []*static.Pod, sortedPods []*static.Pod) ([]byte, error) {
+	g.importPaths = make(map[string]importInfo)
+	g.usedNames = map[string]struct{}{
+		"append": {}, "ctx": {}, "err": {}, "i": {}, "len": {},
+		"nil": {}, "tearDown": {}, "tearDowns": {}, "value": {},
+	}
+	g.pod2Name = make(map[*static.Pod]string)
+	hasFilters := false
+
+	for _, pod := range pods {
+		if len(pod.FilterEntries) >= 1 {
+			hasFilters = true
+		}
+	}
+
+	g.addImport("context", "context")
+	g.addImport("fmt", "fmt")
+	g.addImport("github.com/roy2220/depinj", "depinj")
+
+	if hasFilters {
+		g.addImport("errors", "errors")
+	}
+
+	for _, pod := range pods {
+		g.qualify(pod.Structure.Obj().Pkg())
+	}
+
+	var params []string
+
+	for _, pod := range pods {
+		params = append(params, g.podName(pod)+" "+types.TypeString(pod.Type, g.qualify))
+	}
+
+	g.printf("// %s sets up the given pods as depinj.PodPool.SetUp does, and returns\n", g.FuncName)
+	g.printf("// the function to tear them down as depinj.PodPool.TearDown does.\n")
+	g.printf("func %s(ctx context.Context, %s) (tearDown func(), err error) {\n", g.FuncName, strings.Join(params, ", "))
+	g.printf("var tearDowns []func()\n\n")
+	g.printf("defer func() {\n")
+	g.printf("if err != nil {\n")
+	g.printf("for i := len(tearDowns) - 1; i >= 0; i-- {\n")
+	g.printf("tearDowns[i]()\n")
+	g.printf("}\n")
+	g.printf("}\n")
+	g.printf("}()\n")
+
+	for _, pod := range sortedPods {
+		g.generateSetUp(pod)
+	}
+
+	for i, pod := range sortedPods {
+		if !pod.HasAfterAllSetUpHook {
+			continue
+		}
+
+		podName := g.pod2Name[pod]
+		g.printf("\nif err := %s.AfterAllSetUp(ctx); err != nil {\n", podName)
+
+		for j := i - 1; j >= 0; j-- {
+			if sortedPods[j].HasBeforeAnyTearDownHook {
+				g.printf("%s.BeforeAnyTearDown(ctx)\n", g.pod2Name[sortedPods[j]])
+			}
+		}
+
+		g.printf("return nil, fmt.Errorf(\"depinj: pod after-all-setup hook failed; %%s: %%w\", depinj.DescribePod(%s), err)\n", podName)
+		g.printf("}\n")
+	}
+
+	g.printf("\nreturn func() {\n")
+	hasContext := false
+
+	for i := len(sortedPods) - 1; i >= 0; i-- {
+		if pod := sortedPods[i]; pod.HasBeforeAnyTearDownHook {
+			if !hasContext {
+				g.printf("ctx := context.Background()\n")
+				hasContext = true
+			}
+
+			g.printf("%s.BeforeAnyTearDown(ctx)\n", g.pod2Name[pod])
+		}
+	}
+
+	g.printf("\nfor i := len(tearDowns) - 1; i >= 0; i-- {\n")
+	g.printf("tearDowns[i]()\n")
+	g.printf("}\n")
+	g.printf("}, nil\n")
+	g.printf("}\n")
+	body := g.buffer.Bytes()
+	g.buffer = bytes.Buffer{}
+	g.printf("// Code generated by depinj-gen. DO NOT EDIT.\n\n")
+	g.printf("package %s\n\n", g.Package.Name())
+	g.printf("import (\n")
+	importPaths := make([]string, 0, len(g.importPaths))
+
+	for importPath := range g.importPaths {
+		importPaths = append(importPaths, importPath)
+	}
+
+	sort.Slice(importPaths, func(i, j int) bool {
+		if isStandard1, isStandard2 := isStandardImportPath(importPaths[i]), isStandardImportPath(importPaths[j]); isStandard1 != isStandard2 {
+			return isStandard1
+		}
+
+		return importPaths[i] < importPaths[j]
+	})
+
+	for i, importPath := range importPaths {
+		if i >= 1 && isStandardImportPath(importPaths[i-1]) && !isStandardImportPath(importPath) {
+			g.printf("\n")
+		}
+
+		if importInfo := g.importPaths[importPath]; importInfo.Name == importInfo.PackageName {
+			g.printf("%q\n", importPath)
+		} else {
+			g.printf("%s %q\n", importInfo.Name, importPath)
+		}
+	}
+
+	g.printf(")\n\n")
+	g.buffer.Write(body)
+	return format.Source(g.buffer.Bytes())
+}
+
+func (g *generator) generateSetUp(pod *static.Pod) {
+	podName := g.pod2Name[pod]
+	g.printf("\n// %s\n", pod)
+
+	for _, importEntry := range pod.ImportEntries {
+		exportEntry := importEntry.ExportEntry
+		g.printf("%s = %s\n", g.fieldExpr(pod, &importEntry.Entry), g.fieldExpr(exportEntry.Pod, &exportEntry.Entry))
+	}
+
+	if len(pod.ImportEntries) >= 1 {
+		g.printf("\n")
+	}
+
+	g.printf("if err := %s.SetUp(ctx); err != nil {\n", podName)
+	g.printf("return nil, fmt.Errorf(\"depinj: pod setup failed; %%s: %%w\", depinj.DescribePod(%s), err)\n", podName)
+	g.printf("}\n\n")
+	g.printf("tearDowns = append(tearDowns, %s.TearDown)\n", podName)
+
+	for _, exportEntry := range pod.ExportEntries {
+		if len(exportEntry.FilterEntries) == 0 {
+			continue
+		}
+
+		exportExpr := g.fieldExpr(pod, &exportEntry.Entry)
+		g.printf("\n")
+
+		for _, filterEntry := range exportEntry.FilterEntries {
+			g.printf("%s = &%s\n", g.fieldExpr(filterEntry.Pod, &filterEntry.Entry), exportExpr)
+		}
+
+		for _, filterEntry := range exportEntry.FilterEntries {
+			args := []string{"ctx"}
+
+			if filterEntry.Kind.IsTargeted() {
+				args = append(args, fmt.Sprintf("depinj.FilterTarget{RefID: %q, ExportEntryPath: %q}",
+					exportEntry.RefID, exportEntry.Path))
+			}
+
+			if filterEntry.Kind.IsDecorator() {
+				args = append(args, exportExpr)
+			}
+
+			call := g.methodExpr(filterEntry) + "(" + strings.Join(args, ", ") + ")"
+			filterError := fmt.Sprintf("&depinj.FilterError{FilterEntryPath: %q, ExportEntryPath: %q, Err: err}",
+				filterEntry.Path, exportEntry.Path)
+
+			if filterEntry.Kind.IsDecorator() {
+				g.printf("\nif value, err := %s; err == nil {\n", call)
+				g.printf("%s = value\n", exportExpr)
+				g.printf("} else if !errors.Is(err, depinj.ErrFilterSkipped) {\n")
+			} else {
+				g.printf("\nif err := %s; err != nil && !errors.Is(err, depinj.ErrFilterSkipped) {\n", call)
+			}
+
+			g.printf("return nil, %s\n", filterError)
+			g.printf("}\n")
+		}
+	}
+}
+
+func (g *generator) podName(pod *static.Pod) string {
+	name := lowerInitialism(pod.Structure.Obj().Name())
+
+	for i := 2; g.isNameUsed(name); i++ {
+		name = lowerInitialism(pod.Structure.Obj().Name()) + strconv.Itoa(i)
+	}
+
+	g.usedNames[name] = struct{}{}
+	g.pod2Name[pod] = name
+	return name
+}
+
+func (g *generator) isNameUsed(name string) bool {
+	if _, ok := g.usedNames[name]; ok {
+		return true
+	}
+
+	for _, importInfo := range g.importPaths {
+		if name == importInfo.Name {
+			return true
+		}
+	}
+
+	return token.Lookup(name).IsKeyword()
+}
+
+func (g *generator) fieldExpr(pod *static.Pod, entry *static.Entry) string {
+	return g.pod2Name[pod] + g.selector(pod, entry.FieldPath)
+}
+
+func (g *generator) methodExpr(filterEntry *static.FilterEntry) string {
+	return g.pod2Name[filterEntry.Pod] + g.selector(filterEntry.Pod, filterEntry.MethodPath) + "." + filterEntry.Method.Name()
+}
+
+// selector returns the selector expression of the given field path, the
+// unexported embedded fields are skipped for the pods from other packages,
+// relying on promotion.
+func (g *generator) selector(pod *static.Pod, fieldPath []*types.Var) string {
+	var buffer bytes.Buffer
+	isForeign := pod.Structure.Obj().Pkg() != g.Package
+
+	for _, field := range fieldPath {
+		if isForeign && !field.Exported() {
+			continue
+		}
+
+		buffer.WriteByte('.')
+		buffer.WriteString(field.Name())
+	}
+
+	return buffer.String()
+}
+
+func (g *generator) qualify(pkg *types.Package) string {
+	if pkg == g.Package {
+		return ""
+	}
+
+	return g.addImport(pkg.Path(), pkg.Name())
+}
+
+func (g *generator) addImport(importPath string, packageName string) string {
+	if importInfo, ok := g.importPaths[importPath]; ok {
+		return importInfo.Name
+	}
+
+	name := packageName
+
+	for i := 2; g.isNameUsed(name); i++ {
+		name = packageName + strconv.Itoa(i)
+	}
+
+	g.importPaths[importPath] = importInfo{
+		Name:        name,
+		PackageName: packageName,
+	}
+
+	return name
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buffer, format, args...)
+}
+
+func isStandardImportPath(importPath string) bool {
+	return !strings.Contains(strings.SplitN(importPath, "/", 2)[0], ".")
+}
+
+type importInfo struct {
+	Name        string
+	PackageName string
+}
+
+// lowerInitialism lowers the leading upper case letters of the given name,
+// e.g. `HTTPServer` to `httpServer`.
+func lowerInitialism(name string) string {
+	runes := []rune(name)
+
+	for i := range runes {
+		if !unicode.IsUpper(runes[i]) {
+			break
+		}
+
+		if i >= 1 && i+1 < len(runes) && unicode.IsLower(runes[i+1]) {
+			break
+		}
+
+		runes[i] = unicode.ToLower(runes[i])
+	}
+
+	return string(runes)
+}
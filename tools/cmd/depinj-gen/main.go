@@ -0,0 +1,66 @@
+// Command depinj-gen generates the code which sets up and tears down pods
+// without reflection. The pods are resolved at build time with the same rules
+// and errors as depinj.PodPool, and the generated function sets them up in the
+// same order, runs the filters, calls the hooks and rolls back on errors, as
+// depinj.PodPool.SetUp does.
+//
+// Usage:
+//
+//	depinj-gen [flags] <pod type>...
+//
+// A pod type is the name of a structure type, either in the package of the
+// output file, e.g. `Server`, or qualified with an import path, e.g.
+// `example.com/app/db.DB`. The pod types are listed in the order of
+// depinj.PodPool.AddPod, and the generated function takes the pointers to the
+// pods in the same order, e.g.
+//
+//	//go:generate depinj-gen -func=setUpPods Server example.com/app/db.DB
+//
+// generates
+//
+//	func setUpPods(ctx context.Context, server *Server, db *db.DB) (tearDown func(), err error)
+//
+// Since Pod.ResolveRefLink can't be called at build time, ref links are
+// resolved with the JSON file given by `-reflinks` only, see
+// depinj.RefLinkMap. The pods with config entries and the pods implementing
+// depinj.Namespacer with literal ref ids are unsupported, since the values are
+// unknown at build time.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+)
+
+func main() {
+	var options options
+	flag.StringVar(&options.OutputFileName, "o", "depinj_gen.go", "the name of the output file")
+	flag.StringVar(&options.FuncName, "func", "SetUpPods", "the name of the generated function")
+	flag.StringVar(&options.RefLinkMapFileName, "reflinks", "", "the name of the JSON file mapping ref link names to ref ids")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: depinj-gen [flags] <pod type>...\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	options.PodTypeNames = flag.Args()
+
+	if len(options.PodTypeNames) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	code, err := generate(options, json.Unmarshal)
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "depinj-gen: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := ioutil.WriteFile(options.OutputFileName, code, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "depinj-gen: %v\n", err)
+		os.Exit(1)
+	}
+}
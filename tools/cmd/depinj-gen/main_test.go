@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os/exec"
+	"testing"
+
+	"github.com/roy2220/depinj"
+	"github.com/roy2220/depinj/tools/internal/static"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerate(t *testing.T) {
+	code, err := generate(options{
+		OutputFileName: "testdata/app/depinj_gen.go",
+		FuncName:       "setUpPods",
+		PodTypeNames:   []string{"Server", "DB", "Quota", "Skipper", "Tracer", "Observer", "Config"},
+	}, json.Unmarshal)
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	expectedCode, err := ioutil.ReadFile("testdata/app/depinj_gen.go")
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, string(expectedCode), string(code))
+	}
+}
+
+func TestGeneratedCode(t *testing.T) {
+	output, err := exec.Command("go", "run", "./testdata/app").CombinedOutput()
+
+	if assert.NoError(t, err, "%s", output) {
+		assert.Equal(t, "ok\n", string(output))
+	}
+}
+
+func TestGenerateFailed(t *testing.T) {
+	const pkgPath = "github.com/roy2220/depinj/tools/internal/static/testdata/pods"
+
+	for _, tc := range []struct {
+		PodTypeNames []string
+		Err          error
+		ErrStr       string
+	}{
+		{
+			PodTypeNames: []string{pkgPath + ".C1", pkgPath + ".C2"},
+			Err:          depinj.ErrPodCircularDependency,
+			ErrStr:       "pods.go:",
+		},
+		{
+			PodTypeNames: []string{pkgPath + ".G1"},
+			Err:          static.ErrUnsupported,
+			ErrStr:       "pods.go:",
+		},
+		{
+			PodTypeNames: []string{pkgPath + ".E1"},
+			Err:          depinj.ErrBadExportEntry,
+			ErrStr:       "pods.go:",
+		},
+		{
+			PodTypeNames: []string{"NoSuchPod"},
+			ErrStr:       "NoSuchPod",
+		},
+	} {
+		_, err := generate(options{
+			OutputFileName: "testdata/app/depinj_gen.go",
+			FuncName:       "setUpPods",
+			PodTypeNames:   tc.PodTypeNames,
+		}, json.Unmarshal)
+
+		if !assert.Error(t, err) {
+			continue
+		}
+
+		if tc.Err != nil {
+			assert.True(t, errors.Is(err, tc.Err), "%v", err)
+		}
+
+		assert.Contains(t, err.Error(), tc.ErrStr)
+	}
+}
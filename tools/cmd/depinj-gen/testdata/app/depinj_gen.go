@@ -0,0 +1,117 @@
+// Code generated by depinj-gen. DO NOT EDIT.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/roy2220/depinj"
+)
+
+// setUpPods sets up the given pods as depinj.PodPool.SetUp does, and returns
+// the function to tear them down as depinj.PodPool.TearDown does.
+func setUpPods(ctx context.Context, server *Server, db *DB, quota *Quota, skipper *Skipper, tracer *Tracer, observer *Observer, config *Config) (tearDown func(), err error) {
+	var tearDowns []func()
+
+	defer func() {
+		if err != nil {
+			for i := len(tearDowns) - 1; i >= 0; i-- {
+				tearDowns[i]()
+			}
+		}
+	}()
+
+	// *main.Skipper
+	if err := skipper.SetUp(ctx); err != nil {
+		return nil, fmt.Errorf("depinj: pod setup failed; %s: %w", depinj.DescribePod(skipper), err)
+	}
+
+	tearDowns = append(tearDowns, skipper.TearDown)
+
+	// *main.Tracer
+	if err := tracer.SetUp(ctx); err != nil {
+		return nil, fmt.Errorf("depinj: pod setup failed; %s: %w", depinj.DescribePod(tracer), err)
+	}
+
+	tearDowns = append(tearDowns, tracer.TearDown)
+
+	// *main.Observer
+	if err := observer.SetUp(ctx); err != nil {
+		return nil, fmt.Errorf("depinj: pod setup failed; %s: %w", depinj.DescribePod(observer), err)
+	}
+
+	tearDowns = append(tearDowns, observer.TearDown)
+
+	// *main.Quota
+	if err := quota.SetUp(ctx); err != nil {
+		return nil, fmt.Errorf("depinj: pod setup failed; %s: %w", depinj.DescribePod(quota), err)
+	}
+
+	tearDowns = append(tearDowns, quota.TearDown)
+
+	// *main.Config
+	if err := config.SetUp(ctx); err != nil {
+		return nil, fmt.Errorf("depinj: pod setup failed; %s: %w", depinj.DescribePod(config), err)
+	}
+
+	tearDowns = append(tearDowns, config.TearDown)
+
+	skipper.DSN = &config.DSN
+	tracer.DSN = &config.DSN
+	observer.Hooks.DSN = &config.DSN
+
+	if err := skipper.SkipDSN(ctx); err != nil && !errors.Is(err, depinj.ErrFilterSkipped) {
+		return nil, &depinj.FilterError{FilterEntryPath: "main.Skipper.DSN", ExportEntryPath: "main.Config.DSN", Err: err}
+	}
+
+	if err := tracer.TraceDSN(ctx); err != nil && !errors.Is(err, depinj.ErrFilterSkipped) {
+		return nil, &depinj.FilterError{FilterEntryPath: "main.Tracer.DSN", ExportEntryPath: "main.Config.DSN", Err: err}
+	}
+
+	if err := observer.Hooks.ObserveDSN(ctx, depinj.FilterTarget{RefID: "dsn", ExportEntryPath: "main.Config.DSN"}); err != nil && !errors.Is(err, depinj.ErrFilterSkipped) {
+		return nil, &depinj.FilterError{FilterEntryPath: "main.Observer.Hooks.DSN", ExportEntryPath: "main.Config.DSN", Err: err}
+	}
+
+	quota.Timeout = &config.Timeout
+
+	if value, err := quota.LimitTimeout(ctx, depinj.FilterTarget{RefID: "timeout", ExportEntryPath: "main.Config.Timeout"}, config.Timeout); err == nil {
+		config.Timeout = value
+	} else if !errors.Is(err, depinj.ErrFilterSkipped) {
+		return nil, &depinj.FilterError{FilterEntryPath: "main.Quota.Timeout", ExportEntryPath: "main.Config.Timeout", Err: err}
+	}
+
+	// *main.DB
+	db.DSN = config.DSN
+	db.Timeout = config.Timeout
+
+	if err := db.SetUp(ctx); err != nil {
+		return nil, fmt.Errorf("depinj: pod setup failed; %s: %w", depinj.DescribePod(db), err)
+	}
+
+	tearDowns = append(tearDowns, db.TearDown)
+
+	// *main.Server
+	server.Deps.Conn = db.Conn
+
+	if err := server.SetUp(ctx); err != nil {
+		return nil, fmt.Errorf("depinj: pod setup failed; %s: %w", depinj.DescribePod(server), err)
+	}
+
+	tearDowns = append(tearDowns, server.TearDown)
+
+	if err := db.AfterAllSetUp(ctx); err != nil {
+		return nil, fmt.Errorf("depinj: pod after-all-setup hook failed; %s: %w", depinj.DescribePod(db), err)
+	}
+
+	return func() {
+		ctx := context.Background()
+		server.BeforeAnyTearDown(ctx)
+		db.BeforeAnyTearDown(ctx)
+
+		for i := len(tearDowns) - 1; i >= 0; i-- {
+			tearDowns[i]()
+		}
+	}, nil
+}
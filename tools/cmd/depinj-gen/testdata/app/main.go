@@ -0,0 +1,60 @@
+// Command app checks the code generated by depinj-gen against depinj.PodPool.
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+
+	"github.com/roy2220/depinj"
+)
+
+func main() {
+	for _, fail := range []bool{false, true} {
+		events = nil
+		err1 := runPodPool(fail)
+		events1 := events
+		events = nil
+		err2 := runGeneratedCode(fail)
+		events2 := events
+
+		if !reflect.DeepEqual(events1, events2) || fmt.Sprint(err1) != fmt.Sprint(err2) {
+			fmt.Printf("mismatch (fail=%v):\n\t%q %v\n\t%q %v\n", fail, events1, err1, events2, err2)
+			os.Exit(1)
+		}
+	}
+
+	fmt.Println("ok")
+}
+
+func runPodPool(fail bool) error {
+	var pp depinj.PodPool
+
+	for _, pod := range newPods(fail) {
+		pp.MustAddPod(pod)
+	}
+
+	if err := pp.SetUp(context.Background()); err != nil {
+		return err
+	}
+
+	return pp.TryTearDown()
+}
+
+func runGeneratedCode(fail bool) error {
+	pods := newPods(fail)
+	tearDown, err := setUpPods(context.Background(), pods[0].(*Server), pods[1].(*DB),
+		pods[2].(*Quota), pods[3].(*Skipper), pods[4].(*Tracer), pods[5].(*Observer), pods[6].(*Config))
+
+	if err != nil {
+		return err
+	}
+
+	tearDown()
+	return nil
+}
+
+func newPods(fail bool) []depinj.Pod {
+	return []depinj.Pod{&Server{}, &DB{Fail: fail}, &Quota{}, &Skipper{}, &Tracer{}, &Observer{}, &Config{}}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/roy2220/depinj"
+)
+
+//go:generate go run github.com/roy2220/depinj/tools/cmd/depinj-gen -func=setUpPods Server DB Quota Skipper Tracer Observer Config
+
+var events []string
+
+type Base struct {
+	depinj.DummyPod
+}
+
+func (Base) SetUp(context.Context) error {
+	return nil
+}
+
+type Conn struct {
+	DSN     string
+	Timeout time.Duration
+}
+
+type Config struct {
+	Base
+	DSN     string        `export:"dsn"`
+	Timeout time.Duration `export:"timeout,labels=kind:db"`
+}
+
+func (c *Config) SetUp(context.Context) error {
+	events = append(events, "setup Config")
+	c.DSN = "mem://db"
+	c.Timeout = time.Second
+	return nil
+}
+
+func (c *Config) TearDown() {
+	events = append(events, "teardown Config")
+}
+
+type Tracer struct {
+	Base
+	DSN *string `filter:"dsn,TraceDSN,10,id=trace"`
+}
+
+func (t *Tracer) TraceDSN(context.Context) error {
+	events = append(events, "filter Tracer")
+	*t.DSN += "?trace=1"
+	return nil
+}
+
+type Skipper struct {
+	Base
+	DSN *string `filter:"dsn,SkipDSN,0,before=trace"`
+}
+
+func (s *Skipper) SkipDSN(context.Context) error {
+	events = append(events, "filter Skipper")
+	return depinj.ErrFilterSkipped
+}
+
+type Quota struct {
+	Base
+	Timeout *time.Duration `filter:",LimitTimeout,0,select=kind:db"`
+}
+
+func (q *Quota) LimitTimeout(_ context.Context, target depinj.FilterTarget, timeout time.Duration) (time.Duration, error) {
+	events = append(events, "filter Quota "+target.ExportEntryPath)
+	return timeout / 2, nil
+}
+
+type DB struct {
+	Base
+	DSN     string        `import:"dsn"`
+	Timeout time.Duration `import:",select=kind:db"`
+	Conn    *Conn         `export:""`
+	Fail    bool
+}
+
+func (d *DB) PodName() string { return "primary" }
+
+func (d *DB) SetUp(context.Context) error {
+	events = append(events, "setup DB "+d.DSN+" "+d.Timeout.String())
+
+	if d.Fail {
+		return errors.New("connection refused")
+	}
+
+	d.Conn = &Conn{DSN: d.DSN, Timeout: d.Timeout}
+	return nil
+}
+
+func (d *DB) TearDown() {
+	events = append(events, "teardown DB")
+}
+
+func (d *DB) AfterAllSetUp(context.Context) error {
+	events = append(events, "after-all-setup DB")
+	return nil
+}
+
+func (d *DB) BeforeAnyTearDown(context.Context) {
+	events = append(events, "before-any-teardown DB")
+}
+
+type Hooks struct {
+	DSN *string `filter:"dsn,ObserveDSN,-1"`
+}
+
+func (h *Hooks) ObserveDSN(_ context.Context, target depinj.FilterTarget) error {
+	events = append(events, "filter Observer "+target.RefID+" "+*h.DSN)
+	return nil
+}
+
+type Observer struct {
+	Base
+	Hooks
+}
+
+type Deps struct {
+	Conn *Conn `import:""`
+}
+
+type Server struct {
+	Base
+	Deps
+}
+
+func (s *Server) SetUp(context.Context) error {
+	events = append(events, "setup Server "+s.Conn.DSN)
+	return nil
+}
+
+func (s *Server) TearDown() {
+	events = append(events, "teardown Server")
+}
+
+func (s *Server) BeforeAnyTearDown(context.Context) {
+	events = append(events, "before-any-teardown Server")
+}
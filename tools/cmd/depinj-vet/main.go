@@ -0,0 +1,16 @@
+// Command depinj-vet checks the pods with the analyzer of package
+// github.com/roy2220/depinj/tools/analysis, as a tool of `go vet`, e.g.
+//
+//	go install github.com/roy2220/depinj/tools/cmd/depinj-vet
+//	go vet -vettool=$(which depinj-vet) ./...
+package main
+
+import (
+	"golang.org/x/tools/go/analysis/unitchecker"
+
+	"github.com/roy2220/depinj/tools/analysis"
+)
+
+func main() {
+	unitchecker.Main(analysis.Analyzer)
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/roy2220/depinj"
+)
+
+type command struct {
+	NumArgs int
+	Run     func(w io.Writer, report *report, options options, args []string) error
+}
+
+var commands = map[string]command{
+	"order":   {0, runOrder},
+	"check":   {0, runCheck},
+	"explain": {2, runExplain},
+	"graph":   {0, runGraph},
+}
+
+func runOrder(w io.Writer, report *report, _ options, _ []string) error {
+	if report.Error != "" {
+		return errors.New(report.Error)
+	}
+
+	for i, graphPod := range report.Graph.Pods {
+		fmt.Fprintf(w, "%d. %s\n", i+1, podLabel(&graphPod))
+	}
+
+	return nil
+}
+
+func runCheck(w io.Writer, report *report, _ options, _ []string) error {
+	if report.Error != "" {
+		fmt.Fprintln(w, report.Error)
+		return errors.New("resolution failed")
+	}
+
+	for _, warning := range report.Warnings {
+		fmt.Fprintln(w, warning)
+	}
+
+	if len(report.Warnings) >= 1 {
+		return fmt.Errorf("%d unused export entries or pods", len(report.Warnings))
+	}
+
+	return nil
+}
+
+func runExplain(w io.Writer, report *report, _ options, args []string) error {
+	if report.Error != "" {
+		return errors.New(report.Error)
+	}
+
+	graph := report.Graph
+	x, err := findPod(graph, args[0])
+
+	if err != nil {
+		return err
+	}
+
+	y, err := findPod(graph, args[1])
+
+	if err != nil {
+		return err
+	}
+
+	// the pod `y` is set up before the pod `x`.
+	edges := graph.Explain(y, x)
+
+	if edges == nil {
+		fmt.Fprintf(w, "%s doesn't depend on %s\n", podLabel(&graph.Pods[x]), podLabel(&graph.Pods[y]))
+		return nil
+	}
+
+	fmt.Fprintf(w, "%s depends on %s:\n", podLabel(&graph.Pods[x]), podLabel(&graph.Pods[y]))
+
+	for i := len(edges) - 1; i >= 0; i-- {
+		switch edge := edges[i]; edge.Kind {
+		case depinj.GraphEdgeImport:
+			fmt.Fprintf(w, "\t%s imports %s\n", edge.ToEntryPath, edge.FromEntryPath)
+		case depinj.GraphEdgeFilter:
+			fmt.Fprintf(w, "\t%s is filtered by %s\n", edge.ToEntryPath, edge.FromEntryPath)
+		}
+	}
+
+	return nil
+}
+
+// findPod returns the index of the pod with the given type, which is either
+// the full type, e.g. `*app.Server`, or the type name, e.g. `Server`, or the
+// name of the pod added by depinj.PodPool.AddNamedPod, e.g. `primary`. The
+// pod must be unambiguous.
+func findPod(graph *depinj.Graph, podType string) (int, error) {
+	index := -1
+
+	for i, graphPod := range graph.Pods {
+		typeName := graphPod.Type[strings.LastIndexByte(graphPod.Type, '.')+1:]
+
+		if graphPod.Type != podType && typeName != podType && graphPod.Name != podType {
+			continue
+		}
+
+		if index >= 0 {
+			return 0, fmt.Errorf("ambiguous pod type; podType=%q", podType)
+		}
+
+		index = i
+	}
+
+	if index < 0 {
+		return 0, fmt.Errorf("pod not found; podType=%q", podType)
+	}
+
+	return index, nil
+}
+
+// podLabel returns the label of the given pod, which is the type of the pod,
+// followed by the name of the pod if any, e.g. `*app.DB (primary)`.
+func podLabel(graphPod *depinj.GraphPod) string {
+	if graphPod.Name == "" {
+		return graphPod.Type
+	}
+
+	return graphPod.Type + " (" + graphPod.Name + ")"
+}
+
+func runGraph(w io.Writer, report *report, options options, _ []string) error {
+	if report.Error != "" {
+		return errors.New(report.Error)
+	}
+
+	switch options.Format {
+	case "dot":
+		writeDOT(w, report.Graph)
+	case "mermaid":
+		writeMermaid(w, report.Graph)
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "\t")
+		return encoder.Encode(report.Graph)
+	default:
+		return fmt.Errorf("unknown format; format=%q", options.Format)
+	}
+
+	return nil
+}
+
+// writeDOT writes the graph in DOT, with the edges from the depended pods to
+// the depending pods, labeled with the import entry paths or the filter entry
+// paths.
+func writeDOT(w io.Writer, graph *depinj.Graph) {
+	fmt.Fprintln(w, "digraph depinj {")
+
+	for i, graphPod := range graph.Pods {
+		fmt.Fprintf(w, "\tn%d [label=%q];\n", i, podLabel(&graphPod))
+	}
+
+	for _, edge := range graph.Edges {
+		switch edge.Kind {
+		case depinj.GraphEdgeImport:
+			fmt.Fprintf(w, "\tn%d -> n%d [label=%q];\n", edge.From, edge.To, edge.ToEntryPath)
+		case depinj.GraphEdgeFilter:
+			fmt.Fprintf(w, "\tn%d -> n%d [label=%q, style=dashed];\n", edge.From, edge.To, edge.FromEntryPath)
+		}
+	}
+
+	fmt.Fprintln(w, "}")
+}
+
+// writeMermaid writes the graph in Mermaid, as writeDOT does.
+func writeMermaid(w io.Writer, graph *depinj.Graph) {
+	fmt.Fprintln(w, "graph LR")
+
+	for i, graphPod := range graph.Pods {
+		fmt.Fprintf(w, "\tn%d[\"%s\"]\n", i, podLabel(&graphPod))
+	}
+
+	for _, edge := range graph.Edges {
+		switch edge.Kind {
+		case depinj.GraphEdgeImport:
+			fmt.Fprintf(w, "\tn%d -->|\"%s\"| n%d\n", edge.From, edge.ToEntryPath, edge.To)
+		case depinj.GraphEdgeFilter:
+			fmt.Fprintf(w, "\tn%d -.->|\"%s\"| n%d\n", edge.From, edge.FromEntryPath, edge.To)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/roy2220/depinj"
+)
+
+type options struct {
+	PackagePattern string
+	FuncName       string
+	Tags           string
+	Format         string
+}
+
+// report is the result of the resolution, which is printed in JSON by the
+// temporary program.
+type report struct {
+	Graph    *depinj.Graph `json:"graph,omitempty"`
+	Warnings []string      `json:"warnings,omitempty"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// loadReport builds and runs the temporary program, which calls the
+// registration function and resolves the pods, and decodes the report.
+func loadReport(options options) (*report, error) {
+	pkgPath, pkgDir, err := listPackage(options)
+
+	if err != nil {
+		return nil, err
+	}
+
+	tempDirName, err := ioutil.TempDir("", "depinj")
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer os.RemoveAll(tempDirName)
+	var code bytes.Buffer
+
+	if err := programTemplate.Execute(&code, struct {
+		PackagePath string
+		FuncName    string
+	}{pkgPath, options.FuncName}); err != nil {
+		return nil, err
+	}
+
+	mainFileName := filepath.Join(tempDirName, "main.go")
+
+	if err := ioutil.WriteFile(mainFileName, code.Bytes(), 0644); err != nil {
+		return nil, err
+	}
+
+	// run in the directory of the package, so that the package is imported
+	// within its module.
+	cmd := exec.Command("go", "run", "-tags="+options.Tags, mainFileName)
+	cmd.Dir = pkgDir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+
+	if err != nil {
+		return nil, fmt.Errorf("registration function run failed: %v\n%s", err, stderr.Bytes())
+	}
+
+	var report report
+
+	if err := json.Unmarshal(output, &report); err != nil {
+		return nil, fmt.Errorf("report decode failed: %v", err)
+	}
+
+	return &report, nil
+}
+
+func listPackage(options options) (string, string, error) {
+	cmd := exec.Command("go", "list", "-tags="+options.Tags, "-f", "{{.ImportPath}}\t{{.Dir}}\t{{.Name}}", options.PackagePattern)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+
+	if err != nil {
+		return "", "", fmt.Errorf("package list failed: %v\n%s", err, stderr.Bytes())
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+
+	if len(lines) != 1 {
+		return "", "", fmt.Errorf("not exactly one package; packagePattern=%q", options.PackagePattern)
+	}
+
+	fields := strings.Split(lines[0], "\t")
+
+	if fields[2] == "main" {
+		return "", "", fmt.Errorf("main package unimportable; packagePath=%q", fields[0])
+	}
+
+	return fields[0], fields[1], nil
+}
+
+var programTemplate = template.Must(template.New("").Parse(`// Code generated by depinj. DO NOT EDIT.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/roy2220/depinj"
+
+	target {{ printf "%q" .PackagePath }}
+)
+
+func main() {
+	var pp depinj.PodPool
+	pp.SetStrictMode(depinj.StrictModeWarn)
+	var report struct {
+		Graph    *depinj.Graph ` + "`json:\"graph,omitempty\"`" + `
+		Warnings []string      ` + "`json:\"warnings,omitempty\"`" + `
+		Error    string        ` + "`json:\"error,omitempty\"`" + `
+	}
+
+	if err := register(&pp); err != nil {
+		report.Error = err.Error()
+	} else if graph, err := pp.Graph(); err != nil {
+		report.Error = err.Error()
+	} else {
+		report.Graph = graph
+
+		for _, warning := range pp.Warnings() {
+			report.Warnings = append(report.Warnings, warning.Error())
+		}
+	}
+
+	if err := json.NewEncoder(os.Stdout).Encode(&report); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func register(pp *depinj.PodPool) error {
+	var registrationFunc interface{} = target.{{ .FuncName }}
+
+	switch registrationFunc := registrationFunc.(type) {
+	case func(*depinj.PodPool):
+		registrationFunc(pp)
+		return nil
+	case func(*depinj.PodPool) error:
+		return registrationFunc(pp)
+	default:
+		return fmt.Errorf("registration function type mismatch (expected ` + "`func(*depinj.PodPool)` or `func(*depinj.PodPool) error`, got `%T`" + `)", registrationFunc)
+	}
+}
+`))
@@ -0,0 +1,84 @@
+// Command depinj inspects the dependency graph of the pods added to a pool by
+// a registration function, without setting up the pods.
+//
+// Usage:
+//
+//	depinj [flags] order
+//	depinj [flags] check
+//	depinj [flags] explain <pod type> <pod type>
+//	depinj [flags] graph
+//
+// The registration function is an exported function of the package given by
+// `-pkg`, with the signature `func(*depinj.PodPool)` or
+// `func(*depinj.PodPool) error`, which is usually declared in a file with the
+// build tag given by `-tags`, e.g.
+//
+//	//go:build depinj
+//	// +build depinj
+//
+//	package wiring
+//
+//	func RegisterPods(pp *depinj.PodPool) {
+//		pp.MustAddPod(&app.Server{})
+//		pp.MustAddPod(&db.DB{})
+//	}
+//
+// The package is imported by a temporary program, which calls the registration
+// function and resolves the pods with depinj.PodPool.Graph, so the package
+// can't be a main package.
+//
+// The commands are:
+//
+//	order    prints the pods in the order of setups.
+//	check    prints the resolution error, or the unused export entries and
+//	         pods (see depinj.StrictModeWarn), and fails if there is any.
+//	explain  prints the shortest chain of import and filter edges through
+//	         which the first pod depends on the second pod.
+//	graph    prints the graph in the format given by `-format`.
+//
+// A pod type is given as printed by `order`, e.g. `*app.Server`, or without
+// the package name if it's unambiguous, e.g. `Server`.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+func main() {
+	var options options
+	flag.StringVar(&options.PackagePattern, "pkg", ".", "the package of the registration function")
+	flag.StringVar(&options.FuncName, "func", "RegisterPods", "the name of the registration function")
+	flag.StringVar(&options.Tags, "tags", "depinj", "the comma-separated list of the build tags")
+	flag.StringVar(&options.Format, "format", "dot", "the format of the graph printed by `graph`: dot, mermaid or json")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: depinj [flags] order|check|explain <pod type> <pod type>|graph\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	command, ok := commands[flag.Arg(0)]
+
+	if !ok || flag.NArg()-1 != command.NumArgs {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	report, err := loadReport(options)
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "depinj: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := command.Run(os.Stdout, report, options, flag.Args()[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "depinj: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/depinj"
+)
+
+func TestCommands(t *testing.T) {
+	report, err := loadReport(options{PackagePattern: "./testdata/app", FuncName: "RegisterPods", Tags: "depinj"})
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	for _, tc := range []struct {
+		Format string
+		Args   []string
+		Output string
+		ErrStr string
+	}{
+		{
+			Args: []string{"order"},
+			Output: `1. *app.Tracer
+2. *app.Config
+3. *app.DB
+4. *app.Server
+5. *app.Metrics
+`,
+		},
+		{
+			Args: []string{"check"},
+			Output: `depinj: unused pod: no dependent pod; podType="*app.Server"
+depinj: unused export entry: no import/filter entry; exportEntryPath="app.Metrics.Registry"
+depinj: unused pod: no dependent pod; podType="*app.Metrics"
+`,
+			ErrStr: "3 unused export entries or pods",
+		},
+		{
+			Args: []string{"explain", "*app.Server", "Config"},
+			Output: `*app.Server depends on *app.Config:
+	app.Server.Conn imports app.DB.Conn
+	app.DB.DSN imports app.Config.DSN
+`,
+		},
+		{
+			Args: []string{"explain", "DB", "Tracer"},
+			Output: `*app.DB depends on *app.Tracer:
+	app.DB.DSN imports app.Config.DSN
+	app.Config.DSN is filtered by app.Tracer.DSN
+`,
+		},
+		{
+			Args:   []string{"explain", "Config", "Server"},
+			Output: "*app.Config doesn't depend on *app.Server\n",
+		},
+		{
+			Args:   []string{"explain", "Config", "Client"},
+			ErrStr: `pod not found; podType="Client"`,
+		},
+		{
+			Format: "dot",
+			Args:   []string{"graph"},
+			Output: `digraph depinj {
+	n0 [label="*app.Tracer"];
+	n1 [label="*app.Config"];
+	n2 [label="*app.DB"];
+	n3 [label="*app.Server"];
+	n4 [label="*app.Metrics"];
+	n0 -> n1 [label="app.Tracer.DSN", style=dashed];
+	n1 -> n2 [label="app.DB.DSN"];
+	n2 -> n3 [label="app.Server.Conn"];
+}
+`,
+		},
+		{
+			Format: "mermaid",
+			Args:   []string{"graph"},
+			Output: `graph LR
+	n0["*app.Tracer"]
+	n1["*app.Config"]
+	n2["*app.DB"]
+	n3["*app.Server"]
+	n4["*app.Metrics"]
+	n0 -.->|"app.Tracer.DSN"| n1
+	n1 -->|"app.DB.DSN"| n2
+	n2 -->|"app.Server.Conn"| n3
+`,
+		},
+		{
+			Format: "svg",
+			Args:   []string{"graph"},
+			ErrStr: `unknown format; format="svg"`,
+		},
+	} {
+		var output bytes.Buffer
+		err := commands[tc.Args[0]].Run(&output, report, options{Format: tc.Format}, tc.Args[1:])
+
+		if tc.ErrStr == "" {
+			assert.NoError(t, err, "%v", tc.Args)
+		} else if assert.Error(t, err, "%v", tc.Args) {
+			assert.Contains(t, err.Error(), tc.ErrStr)
+		}
+
+		assert.Equal(t, tc.Output, output.String(), "%v", tc.Args)
+	}
+}
+
+func TestCommandsFailed(t *testing.T) {
+	report, err := loadReport(options{PackagePattern: "./testdata/bad", FuncName: "RegisterPods", Tags: "depinj"})
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	var output bytes.Buffer
+	err = runCheck(&output, report, options{}, nil)
+	assert.EqualError(t, err, "resolution failed")
+	assert.Equal(t, `depinj: bad import entry: export entry not found by ref id; importEntryPath="bad.Server.DSN" refID="dsn"`+"\n", output.String())
+
+	_, err = loadReport(options{PackagePattern: "./testdata/bad", FuncName: "RegisterPods", Tags: ""})
+	assert.Error(t, err)
+}
+
+func TestLoadReportFailed(t *testing.T) {
+	_, err := loadReport(options{PackagePattern: "./testdata/app", FuncName: "NewServer", Tags: "depinj"})
+
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "NewServer")
+	}
+}
+
+func TestFindPod(t *testing.T) {
+	graph := &depinj.Graph{Pods: []depinj.GraphPod{
+		{Type: "*app.Server"},
+		{Type: "*db.DB", Name: "primary"},
+		{Type: "*db.DB", Name: "replica"},
+	}}
+
+	for _, tc := range []struct {
+		PodType string
+		Index   int
+		ErrStr  string
+	}{
+		{PodType: "*app.Server", Index: 0},
+		{PodType: "Server", Index: 0},
+		{PodType: "replica", Index: 2},
+		{PodType: "*db.DB", ErrStr: `ambiguous pod type; podType="*db.DB"`},
+		{PodType: "Client", ErrStr: `pod not found; podType="Client"`},
+	} {
+		index, err := findPod(graph, tc.PodType)
+
+		if tc.ErrStr == "" {
+			if assert.NoError(t, err) {
+				assert.Equal(t, tc.Index, index)
+			}
+		} else {
+			assert.EqualError(t, err, tc.ErrStr)
+		}
+	}
+
+	assert.Equal(t, "*db.DB (primary)", podLabel(&graph.Pods[1]))
+}
@@ -0,0 +1,38 @@
+// Package app provides the pods for the tests of depinj.
+package app
+
+import (
+	"context"
+
+	"github.com/roy2220/depinj"
+)
+
+type Conn struct{}
+
+type Config struct {
+	depinj.DummyPod
+	DSN string `export:"dsn"`
+}
+
+type Tracer struct {
+	depinj.DummyPod
+	DSN *string `filter:"dsn,TraceDSN,0"`
+}
+
+func (Tracer) TraceDSN(context.Context) error { return nil }
+
+type DB struct {
+	depinj.DummyPod
+	DSN  string `import:"dsn"`
+	Conn *Conn  `export:""`
+}
+
+type Server struct {
+	depinj.DummyPod
+	Conn *Conn `import:""`
+}
+
+type Metrics struct {
+	depinj.DummyPod
+	Registry string `export:"registry"`
+}
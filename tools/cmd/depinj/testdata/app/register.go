@@ -0,0 +1,17 @@
+//go:build depinj
+// +build depinj
+
+package app
+
+import (
+	"github.com/roy2220/depinj"
+)
+
+// RegisterPods adds the pods to the given pool.
+func RegisterPods(pp *depinj.PodPool) {
+	pp.MustAddPod(&Server{})
+	pp.MustAddPod(&DB{})
+	pp.MustAddPod(&Tracer{})
+	pp.MustAddPod(&Config{})
+	pp.MustAddPod(&Metrics{})
+}
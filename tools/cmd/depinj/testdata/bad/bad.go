@@ -0,0 +1,20 @@
+//go:build depinj
+// +build depinj
+
+// Package bad provides the pods for the tests of depinj, which can't be
+// resolved.
+package bad
+
+import (
+	"github.com/roy2220/depinj"
+)
+
+type Server struct {
+	depinj.DummyPod
+	DSN string `import:"dsn"`
+}
+
+// RegisterPods adds the pods to the given pool.
+func RegisterPods(pp *depinj.PodPool) error {
+	return pp.AddPod(&Server{})
+}
@@ -0,0 +1,20 @@
+module github.com/roy2220/depinj/tools
+
+go 1.19
+
+require (
+	github.com/roy2220/depinj v0.0.0-00010101000000-000000000000
+	github.com/stretchr/testify v1.6.0
+	golang.org/x/tools v0.24.1
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	golang.org/x/mod v0.20.0 // indirect
+	golang.org/x/sync v0.8.0 // indirect
+	gopkg.in/yaml.v3 v3.0.0-20200313102051-9f266ea9e77c // indirect
+)
+
+// the tools follow the library in the same repository.
+replace github.com/roy2220/depinj => ../
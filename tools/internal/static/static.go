@@ -0,0 +1,1363 @@
+// Package static implements the resolution of pods on their types instead of
+// their values, i.e. at build time, with the same rules and errors as the
+// resolution of depinj.PodPool.
+package static
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/token"
+	"go/types"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/types/typeutil"
+
+	"github.com/roy2220/depinj"
+	"github.com/roy2220/depinj/internal/tagsyntax"
+)
+
+// Pod represents the type of a pod, i.e. a pointer to a named structure.
+type Pod struct {
+	// ParsePod
+	Type                     types.Type
+	Structure                *types.Named
+	ImportEntries            []*ImportEntry
+	ExportEntries            []*ExportEntry
+	FilterEntries            []*FilterEntry
+	ConfigEntries            []*ConfigEntry
+	IsNamespacer             bool
+	HasAfterAllSetUpHook     bool
+	HasBeforeAnyTearDownHook bool
+
+	keepsParsing bool
+	entryErrs    []error
+}
+
+// ParsePod parses the given type as a pod. The errors are the same as the
+// ones returned by depinj.PodPool.AddPod.
+func ParsePod(typ types.Type) (*Pod, error) {
+	p := Pod{Type: typ}
+
+	if err := p.parse(); err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+// CheckPod parses the given type as a pod, as ParsePod does, but doesn't stop
+// at the first bad entry, and returns all the errors.
+func CheckPod(typ types.Type) []error {
+	p := Pod{Type: typ, keepsParsing: true}
+
+	if err := p.parse(); err != nil {
+		return append(p.entryErrs, err)
+	}
+
+	return p.entryErrs
+}
+
+func (p *Pod) parse() error {
+	pointer, ok := p.Type.(*types.Pointer)
+
+	if !ok {
+		return p.error(fmt.Errorf("%w: non-pointer type; podType=%q", depinj.ErrInvalidPod, typeString(p.Type)))
+	}
+
+	structure, ok := pointer.Elem().(*types.Named)
+
+	if !ok || !isStructure(structure) {
+		return p.error(fmt.Errorf("%w: non-structure pointer type; podType=%q", depinj.ErrInvalidPod, typeString(p.Type)))
+	}
+
+	p.Structure = structure
+
+	if err := p.parseStructure(nil, structure); err != nil {
+		return err
+	}
+
+	if len(p.entryErrs) >= 1 {
+		return nil
+	}
+
+	if len(p.ImportEntries)+len(p.ExportEntries)+len(p.FilterEntries)+len(p.ConfigEntries) == 0 {
+		return p.error(fmt.Errorf("%w: no import/export/filter/config entry; podType=%q", depinj.ErrInvalidPod, typeString(p.Type)))
+	}
+
+	methodSet := types.NewMethodSet(p.Type)
+	p.IsNamespacer = hasMethod(methodSet, "RefIDNamespace", nil, []types.Type{types.Typ[types.String]})
+	p.HasAfterAllSetUpHook = hasMethod(methodSet, "AfterAllSetUp", []types.Type{nil}, []types.Type{errorType})
+	p.HasBeforeAnyTearDownHook = hasMethod(methodSet, "BeforeAnyTearDown", []types.Type{nil}, nil)
+	return nil
+}
+
+// Pos returns the position of the pod structure, or token.NoPos if the pod
+// is invalid.
+func (p *Pod) Pos() token.Pos {
+	if p.Structure == nil {
+		return token.NoPos
+	}
+
+	return p.Structure.Obj().Pos()
+}
+
+// String returns the type of the pod, e.g. `*app.Server`.
+func (p *Pod) String() string {
+	return typeString(p.Type)
+}
+
+func (p *Pod) parseStructure(parentFieldInfo *fieldInfo, structureType types.Type) error {
+	structure := structureType.Underlying().(*types.Struct)
+	fieldInfo := fieldInfo{
+		Parent:        parentFieldInfo,
+		StructureType: structureType,
+	}
+
+	for i, n := 0, structure.NumFields(); i < n; i++ {
+		fieldInfo.Field = structure.Field(i)
+		fieldInfo.Tag = reflect.StructTag(structure.Tag(i))
+
+		if innerStructureType, ok, err := fieldInfo.inlineStructure(); ok {
+			if err := p.parseStructure(&fieldInfo, innerStructureType); err != nil {
+				return err
+			}
+
+			continue
+		} else if err != nil {
+			if err := p.entryError(err); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		var importEntry ImportEntry
+
+		if ok, err := importEntry.parseField(&fieldInfo); ok {
+			p.ImportEntries = append(p.ImportEntries, &importEntry)
+			continue
+		} else if err != nil {
+			if err := p.entryError(err); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		var exportEntry ExportEntry
+
+		if ok, err := exportEntry.parseField(&fieldInfo); ok {
+			p.ExportEntries = append(p.ExportEntries, &exportEntry)
+			continue
+		} else if err != nil {
+			if err := p.entryError(err); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		var filterEntry FilterEntry
+
+		if ok, err := filterEntry.parseField(&fieldInfo); ok {
+			p.FilterEntries = append(p.FilterEntries, &filterEntry)
+			continue
+		} else if err != nil {
+			if err := p.entryError(err); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		var configEntry ConfigEntry
+
+		if ok, err := configEntry.parseField(&fieldInfo); ok {
+			p.ConfigEntries = append(p.ConfigEntries, &configEntry)
+			continue
+		} else if err != nil {
+			if err := p.entryError(err); err != nil {
+				return err
+			}
+
+			continue
+		}
+	}
+
+	return nil
+}
+
+func (p *Pod) entryError(err error) error {
+	if !p.keepsParsing {
+		return err
+	}
+
+	p.entryErrs = append(p.entryErrs, err)
+	return nil
+}
+
+func (p *Pod) resolve1(context *resolution12Context) error {
+	for _, importEntry := range p.ImportEntries {
+		if err := importEntry.resolve1(context, p); err != nil {
+			return err
+		}
+	}
+
+	for _, exportEntry := range p.ExportEntries {
+		if err := exportEntry.resolve1(context, p); err != nil {
+			return err
+		}
+	}
+
+	for _, filterEntry := range p.FilterEntries {
+		if err := filterEntry.resolve1(context, p); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *Pod) resolve2(context *resolution12Context) error {
+	for _, importEntry := range p.ImportEntries {
+		if err := importEntry.resolve2(context); err != nil {
+			return err
+		}
+	}
+
+	for _, filterEntry := range p.FilterEntries {
+		if err := filterEntry.resolve2(context); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *Pod) resolve3(context *resolution3Context, targetEntry *Entry) error {
+	switch podState := context.EnterPod(p, targetEntry); podState {
+	case resolution3PodLeft:
+		context.LeavePod()
+		return nil
+	case resolution3PodEntered:
+		return context.Error(fmt.Errorf("%w; stackTrace=%q", depinj.ErrPodCircularDependency, context.DumpStack()))
+	}
+
+	for _, importEntry := range p.ImportEntries {
+		context.SetActiveEntry(&importEntry.Entry)
+		exportEntry := importEntry.ExportEntry
+
+		if err := exportEntry.Pod.resolve3(context, &exportEntry.Entry); err != nil {
+			return err
+		}
+	}
+
+	for _, exportEntry := range p.ExportEntries {
+		context.SetActiveEntry(&exportEntry.Entry)
+
+		if err := exportEntry.sortFilterEntries(); err != nil {
+			return err
+		}
+
+		for _, filterEntry := range exportEntry.FilterEntries {
+			if filterEntry.Pod == p {
+				continue
+			}
+
+			if err := filterEntry.Pod.resolve3(context, &filterEntry.Entry); err != nil {
+				return err
+			}
+		}
+	}
+
+	context.LeavePod()
+	context.AppendPod(p)
+	return nil
+}
+
+func (p *Pod) error(err error) error {
+	return &Error{Pos: p.Pos(), Err: err}
+}
+
+// Resolve resolves the given pods, in the order of depinj.PodPool.AddPod, and
+// returns the pods in the order of setups. The ref links are resolved with the
+// given ref link resolver, which can be nil, since Pod.ResolveRefLink can't be
+// called statically. The errors are the same as the ones returned by
+// depinj.PodPool.SetUp, except that the literal ref ids of the pods
+// implementing depinj.Namespacer fail with ErrUnsupported, since the
+// namespaces are unknown statically. The pods can't be resolved twice.
+func Resolve(pods []*Pod, refLinkResolver depinj.RefLinkResolver) ([]*Pod, error) {
+	{
+		context := new(resolution12Context).Init(refLinkResolver)
+
+		for _, pod := range pods {
+			if err := pod.resolve1(context); err != nil {
+				return nil, err
+			}
+		}
+
+		for _, pod := range pods {
+			if err := pod.resolve2(context); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	context := new(resolution3Context).Init()
+
+	for _, pod := range pods {
+		if err := pod.resolve3(context, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	return context.Pods(), nil
+}
+
+type fieldInfo struct {
+	Parent        *fieldInfo
+	StructureType types.Type
+	Field         *types.Var
+	Tag           reflect.StructTag
+}
+
+func (fi *fieldInfo) Path() string {
+	if fi.Parent == nil {
+		return typeString(fi.StructureType) + "." + fi.Field.Name()
+	}
+
+	return fi.Parent.Path() + "." + fi.Field.Name()
+}
+
+func (fi *fieldInfo) FieldPath() []*types.Var {
+	if fi.Parent == nil {
+		return []*types.Var{fi.Field}
+	}
+
+	return append(fi.Parent.FieldPath(), fi.Field)
+}
+
+// inlineStructure returns the type of the structure inlined by the field, as
+// fieldInfo.InlineStructure of depinj does. Unlike depinj, the nil pointer
+// fields can't be checked statically.
+func (fi *fieldInfo) inlineStructure() (types.Type, bool, error) {
+	tag, isTagged := fi.Tag.Lookup("depinj")
+
+	if isTagged && tag != "inline" {
+		return nil, false, fi.error(fmt.Errorf("%w: unknown depinj tag; fieldPath=%q tag=%q",
+			depinj.ErrInvalidPod, fi.Path(), tag))
+	}
+
+	if !isTagged && !fi.Field.Embedded() {
+		return nil, false, nil
+	}
+
+	structureType := fi.Field.Type()
+
+	if pointer, ok := structureType.(*types.Pointer); ok {
+		structureType = pointer.Elem()
+	}
+
+	if !isStructure(structureType) {
+		if isTagged {
+			return nil, false, fi.error(fmt.Errorf("%w: non-structure inline field; fieldPath=%q",
+				depinj.ErrInvalidPod, fi.Path()))
+		}
+
+		return nil, false, nil
+	}
+
+	if _, isPointer := fi.Field.Type().(*types.Pointer); !isTagged && isPointer && !HasEntry(structureType.Underlying().(*types.Struct)) {
+		return nil, false, nil
+	}
+
+	if isTagged && !fi.Field.Embedded() && !fi.Field.Exported() {
+		return nil, false, fi.error(fmt.Errorf("%w: inline field unexported; fieldPath=%q",
+			depinj.ErrInvalidPod, fi.Path()))
+	}
+
+	for other := fi; other != nil; other = other.Parent {
+		if types.Identical(other.StructureType, structureType) {
+			return nil, false, fi.error(fmt.Errorf("%w: recursive structure; fieldPath=%q",
+				depinj.ErrInvalidPod, fi.Path()))
+		}
+	}
+
+	return structureType, true, nil
+}
+
+// HasEntry returns whether the given structure, or any structure inlined into
+// it, has import/export/filter/config entries.
+func HasEntry(structure *types.Struct) bool {
+	return hasEntry(structure, make(map[*types.Struct]struct{}))
+}
+
+func hasEntry(structure *types.Struct, visitedStructures map[*types.Struct]struct{}) bool {
+	if _, ok := visitedStructures[structure]; ok {
+		return false
+	}
+
+	visitedStructures[structure] = struct{}{}
+
+	for i, n := 0, structure.NumFields(); i < n; i++ {
+		field := structure.Field(i)
+		tag := reflect.StructTag(structure.Tag(i))
+
+		if _, ok := tag.Lookup("depinj"); ok || field.Embedded() {
+			fieldType := field.Type()
+
+			if pointer, ok := fieldType.(*types.Pointer); ok {
+				fieldType = pointer.Elem()
+			}
+
+			if innerStructure, ok := fieldType.Underlying().(*types.Struct); ok {
+				if hasEntry(innerStructure, visitedStructures) {
+					return true
+				}
+
+				continue
+			}
+		}
+
+		for _, fieldTagKey := range [...]string{"import", "export", "filter", "config"} {
+			if _, ok := tag.Lookup(fieldTagKey); ok {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (fi *fieldInfo) error(err error) error {
+	return &Error{Pos: fi.Field.Pos(), Err: err}
+}
+
+// Entry represents an import/export/filter/config entry.
+type Entry struct {
+	// ParseField
+	Path string
+
+	// FieldPath is the path of the fields from the pod structure to the
+	// field of the entry, through the embedded or inline structures.
+	FieldPath []*types.Var
+
+	FieldType types.Type
+	RawRefID  string
+
+	// Resolve
+	RefID string
+}
+
+// Field returns the field of the entry.
+func (e *Entry) Field() *types.Var {
+	return e.FieldPath[len(e.FieldPath)-1]
+}
+
+// Pos returns the position of the field of the entry.
+func (e *Entry) Pos() token.Pos {
+	return e.Field().Pos()
+}
+
+// Selector returns the selector expression of the field of the entry, without
+// the operand, e.g. `.Base.DB`.
+func (e *Entry) Selector() string {
+	var buffer bytes.Buffer
+
+	for _, field := range e.FieldPath {
+		buffer.WriteByte('.')
+		buffer.WriteString(field.Name())
+	}
+
+	return buffer.String()
+}
+
+func (e *Entry) parseField(fieldInfo *fieldInfo, fieldTagKey string) ([]string, bool) {
+	fieldTag, ok := fieldInfo.Tag.Lookup(fieldTagKey)
+
+	if !ok {
+		return nil, false
+	}
+
+	e.Path = fieldInfo.Path()
+	e.FieldPath = fieldInfo.FieldPath()
+	e.FieldType = fieldInfo.Field.Type()
+	args := strings.Split(fieldTag, ",")
+	e.RawRefID = args[0]
+	return args, true
+}
+
+func (e *Entry) resolveRefLink(context *resolution12Context, pod *Pod) (string, error) {
+	if refLink := e.RawRefID; tagsyntax.IsRefLink(refLink) {
+		refID, ok := context.ResolveRefLink(refLink)
+
+		if !ok {
+			return refLink, nil
+		}
+
+		e.RefID = refID
+		return "", nil
+	}
+
+	if e.RawRefID != "" && pod.IsNamespacer {
+		return "", e.error(fmt.Errorf("%w: namespace of literal ref id; entryPath=%q podType=%q",
+			ErrUnsupported, e.Path, pod))
+	}
+
+	e.RefID = e.RawRefID
+	return "", nil
+}
+
+func (e *Entry) error(err error) error {
+	return &Error{Pos: e.Pos(), Err: err}
+}
+
+// ImportEntry represents an import entry.
+type ImportEntry struct {
+	Entry
+
+	// ParseField
+	Selector map[string]string
+
+	// Resolve
+	Pod         *Pod
+	ExportEntry *ExportEntry
+}
+
+func (ie *ImportEntry) parseField(fieldInfo *fieldInfo) (bool, error) {
+	args, ok := ie.Entry.parseField(fieldInfo, "import")
+
+	if !ok {
+		return false, nil
+	}
+
+	if !fieldInfo.Field.Exported() {
+		return false, ie.error(fmt.Errorf("%w: field unexported; importEntryPath=%q",
+			depinj.ErrBadImportEntry, ie.Path))
+	}
+
+	for _, option := range args[1:] {
+		key, value := tagsyntax.SplitOption(option)
+
+		switch key {
+		case "select":
+			var err error
+			ie.Selector, err = tagsyntax.ParseLabels(value)
+
+			if err != nil {
+				return false, ie.error(fmt.Errorf("%w: selector parse failed; importEntryPath=%q option=%q: %v",
+					depinj.ErrBadImportEntry, ie.Path, option, err))
+			}
+		default:
+			// unknown options are ignored, as depinj does.
+		}
+	}
+
+	return true, nil
+}
+
+func (ie *ImportEntry) resolve1(context *resolution12Context, pod *Pod) error {
+	ie.Pod = pod
+	refLink, err := ie.resolveRefLink(context, pod)
+
+	if err != nil {
+		return err
+	}
+
+	if refLink != "" {
+		return ie.error(fmt.Errorf("%w: unresolvable ref link; importEntryPath=%q refLink=%q",
+			depinj.ErrBadImportEntry, ie.Path, refLink))
+	}
+
+	return nil
+}
+
+func (ie *ImportEntry) resolve2(context *resolution12Context) error {
+	if len(ie.Selector) >= 1 {
+		var exportEntries []*ExportEntry
+
+		for _, exportEntry := range context.ExportEntries() {
+			if types.Identical(exportEntry.FieldType, ie.FieldType) && (ie.RefID == "" || exportEntry.RefID == ie.RefID) &&
+				tagsyntax.HasLabels(exportEntry.Labels, ie.Selector) {
+				exportEntries = append(exportEntries, exportEntry)
+			}
+		}
+
+		switch len(exportEntries) {
+		case 0:
+			return ie.error(fmt.Errorf("%w: export entry not found by labels; importEntryPath=%q fieldType=%q selector=%q",
+				depinj.ErrBadImportEntry, ie.Path, typeString(ie.FieldType), tagsyntax.FormatLabels(ie.Selector)))
+		case 1:
+			ie.ExportEntry = exportEntries[0]
+		default:
+			var exportEntryPaths []string
+
+			for _, exportEntry := range exportEntries {
+				exportEntryPaths = append(exportEntryPaths, exportEntry.Path)
+			}
+
+			return ie.error(fmt.Errorf("%w: ambiguous export entries by labels; importEntryPath=%q fieldType=%q selector=%q exportEntryPaths=%q",
+				depinj.ErrBadImportEntry, ie.Path, typeString(ie.FieldType), tagsyntax.FormatLabels(ie.Selector), exportEntryPaths))
+		}
+	} else if ie.RefID == "" {
+		var ok bool
+		ie.ExportEntry, ok = context.FindExportEntryByFieldType(ie.FieldType)
+
+		if !ok {
+			return ie.error(fmt.Errorf("%w: export entry not found by field type; importEntryPath=%q fieldType=%q",
+				depinj.ErrBadImportEntry, ie.Path, typeString(ie.FieldType)))
+		}
+	} else {
+		var ok bool
+		ie.ExportEntry, ok = context.FindExportEntryByRefID(ie.RefID)
+
+		if !ok {
+			return ie.error(fmt.Errorf("%w: export entry not found by ref id; importEntryPath=%q refID=%q",
+				depinj.ErrBadImportEntry, ie.Path, ie.RefID))
+		}
+
+		if expectedFieldType := ie.ExportEntry.FieldType; !types.Identical(ie.FieldType, expectedFieldType) {
+			return ie.error(fmt.Errorf("%w: field type mismatch; importEntryPath=%q fieldType=%q expectedFieldType=%q exportEntryPath=%q",
+				depinj.ErrBadImportEntry, ie.Path, typeString(ie.FieldType), typeString(expectedFieldType), ie.ExportEntry.Path))
+		}
+	}
+
+	ie.ExportEntry.ImportEntries = append(ie.ExportEntry.ImportEntries, ie)
+	return nil
+}
+
+// ExportEntry represents an export entry.
+type ExportEntry struct {
+	Entry
+
+	// ParseField
+	Labels map[string]string
+
+	// Resolve
+	Pod           *Pod
+	ImportEntries []*ImportEntry
+	FilterEntries []*FilterEntry
+}
+
+func (ee *ExportEntry) parseField(fieldInfo *fieldInfo) (bool, error) {
+	args, ok := ee.Entry.parseField(fieldInfo, "export")
+
+	if !ok {
+		return false, nil
+	}
+
+	if !fieldInfo.Field.Exported() {
+		return false, ee.error(fmt.Errorf("%w: field unexported; exportEntryPath=%q",
+			depinj.ErrBadExportEntry, ee.Path))
+	}
+
+	for _, option := range args[1:] {
+		key, value := tagsyntax.SplitOption(option)
+
+		switch key {
+		case "labels":
+			var err error
+			ee.Labels, err = tagsyntax.ParseLabels(value)
+
+			if err != nil {
+				return false, ee.error(fmt.Errorf("%w: labels parse failed; exportEntryPath=%q option=%q: %v",
+					depinj.ErrBadExportEntry, ee.Path, option, err))
+			}
+		default:
+			// unknown options are ignored, as depinj does.
+		}
+	}
+
+	return true, nil
+}
+
+func (ee *ExportEntry) resolve1(context *resolution12Context, pod *Pod) error {
+	ee.Pod = pod
+	refLink, err := ee.resolveRefLink(context, pod)
+
+	if err != nil {
+		return err
+	}
+
+	if refLink != "" {
+		return ee.error(fmt.Errorf("%w: unresolvable ref link; exportEntryPath=%q refLink=%q",
+			depinj.ErrBadExportEntry, ee.Path, refLink))
+	}
+
+	if ee.RefID == "" {
+		if conflicting, ok := context.AddExportEntryByFieldType(ee, ee.FieldType); !ok {
+			return ee.error(fmt.Errorf("%w: duplicate field type; exportEntryPath=%q conflictingExportEntryPath=%q fieldType=%q",
+				depinj.ErrBadExportEntry, ee.Path, conflicting.Path, typeString(ee.FieldType)))
+		}
+	} else {
+		if conflicting, ok := context.AddExportEntryByRefID(ee, ee.RefID); !ok {
+			return ee.error(fmt.Errorf("%w: duplicate ref id; exportEntryPath=%q conflictingExportEntryPath=%q refID=%q",
+				depinj.ErrBadExportEntry, ee.Path, conflicting.Path, ee.RefID))
+		}
+	}
+
+	return nil
+}
+
+func (ee *ExportEntry) sortFilterEntries() error {
+	filterEntries := ee.FilterEntries
+	filters := make([]tagsyntax.Filter, len(filterEntries))
+
+	for i, filterEntry := range filterEntries {
+		filters[i] = tagsyntax.Filter{
+			Path:      filterEntry.Path,
+			ID:        filterEntry.ID,
+			Priority:  filterEntry.Priority,
+			Seq:       filterEntry.Seq,
+			BeforeIDs: filterEntry.BeforeIDs,
+			AfterIDs:  filterEntry.AfterIDs,
+		}
+	}
+
+	indexes, filterEntryPaths := tagsyntax.SortFilters(filters)
+
+	if indexes == nil {
+		return ee.error(fmt.Errorf("%w; exportEntryPath=%q filterEntryPaths=%q",
+			depinj.ErrFilterCircularConstraint, ee.Path, filterEntryPaths))
+	}
+
+	sortedFilterEntries := make([]*FilterEntry, len(indexes))
+
+	for i, index := range indexes {
+		sortedFilterEntries[i] = filterEntries[index]
+	}
+
+	copy(filterEntries, sortedFilterEntries)
+	return nil
+}
+
+// FilterEntry represents a filter entry.
+type FilterEntry struct {
+	Entry
+
+	// ParseField
+	Method    *types.Func
+	Kind      FilterKind
+	Priority  int
+	ID        string
+	BeforeIDs []string
+	AfterIDs  []string
+	Selector  map[string]string
+
+	// MethodPath is the path of the fields from the pod structure to the
+	// structure defining the method, through the embedded or inline
+	// structures.
+	MethodPath []*types.Var
+
+	// Resolve
+	Pod           *Pod
+	Seq           int
+	ExportEntries []*ExportEntry
+}
+
+// FilterKind represents the kind of the signature of a filter method.
+type FilterKind int
+
+const (
+	// FilterPlain is the kind of `func(context.Context) error`.
+	FilterPlain FilterKind = iota
+
+	// FilterTargeted is the kind of `func(context.Context, depinj.FilterTarget) error`.
+	FilterTargeted
+
+	// FilterDecorator is the kind of `func(context.Context, T) (T, error)`.
+	FilterDecorator
+
+	// FilterTargetedDecorator is the kind of
+	// `func(context.Context, depinj.FilterTarget, T) (T, error)`.
+	FilterTargetedDecorator
+)
+
+// IsDecorator returns true if the filter method returns a new value.
+func (fk FilterKind) IsDecorator() bool {
+	return fk == FilterDecorator || fk == FilterTargetedDecorator
+}
+
+// IsTargeted returns true if the filter method takes a depinj.FilterTarget.
+func (fk FilterKind) IsTargeted() bool {
+	return fk == FilterTargeted || fk == FilterTargetedDecorator
+}
+
+func (fe *FilterEntry) parseField(fieldInfo *fieldInfo) (bool, error) {
+	args, ok := fe.Entry.parseField(fieldInfo, "filter")
+
+	if !ok {
+		return false, nil
+	}
+
+	if !fieldInfo.Field.Exported() {
+		return false, fe.error(fmt.Errorf("%w: field unexported; filterEntryPath=%q",
+			depinj.ErrBadFilterEntry, fe.Path))
+	}
+
+	pointer, ok := fe.FieldType.Underlying().(*types.Pointer)
+
+	if !ok {
+		return false, fe.error(fmt.Errorf("%w: non-pointer field type; filterEntryPath=%q fieldType=%q",
+			depinj.ErrBadFilterEntry, fe.Path, typeString(fe.FieldType)))
+	}
+
+	if len(args) < 2 {
+		return false, fe.error(fmt.Errorf("%w: missing argument `methodName`; filterEntryPath=%q",
+			depinj.ErrBadFilterEntry, fe.Path))
+	}
+
+	methodName := args[1]
+	fe.Method = lookUpMethod(types.NewMethodSet(types.NewPointer(fieldInfo.StructureType)), methodName)
+
+	if fe.Method == nil {
+		return false, fe.error(fmt.Errorf("%w: method undefined or unexported; filterEntryPath=%q methodName=%q",
+			depinj.ErrBadFilterEntry, fe.Path, methodName))
+	}
+
+	if fieldInfo.Parent != nil {
+		fe.MethodPath = fieldInfo.Parent.FieldPath()
+	}
+
+	signature := fe.Method.Type().(*types.Signature)
+	valueType := pointer.Elem()
+	fe.Kind, ok = filterKindOf(signature, valueType)
+
+	if !ok {
+		return false, fe.error(fmt.Errorf("%w: function type mismatch (expected `func(context.Context) error` or `func(context.Context, %s) (%s, error)`, got `%s`); filterEntryPath=%q methodName=%q",
+			depinj.ErrBadFilterEntry, typeString(valueType), typeString(valueType), signatureString(signature), fe.Path, methodName))
+	}
+
+	if len(args) < 3 {
+		return false, fe.error(fmt.Errorf("%w: missing argument `priority`; filterEntryPath=%q",
+			depinj.ErrBadFilterEntry, fe.Path))
+	}
+
+	options := args[2:]
+
+	// the priority is optional if any option follows, e.g. `before=auth_filter`
+	if priorityStr := options[0]; !strings.Contains(priorityStr, "=") {
+		var err error
+		fe.Priority, err = strconv.Atoi(priorityStr)
+
+		if err != nil {
+			return false, fe.error(fmt.Errorf("%w: priority parse failed; filterEntryPath=%q priorityStr=%q: %v",
+				depinj.ErrBadFilterEntry, fe.Path, priorityStr, err))
+		}
+
+		options = options[1:]
+	}
+
+	for _, option := range options {
+		key, value := tagsyntax.SplitOption(option)
+
+		if (key == "id" || key == "before" || key == "after") && value == "" {
+			return false, fe.error(fmt.Errorf("%w: empty filter id; filterEntryPath=%q option=%q",
+				depinj.ErrBadFilterEntry, fe.Path, option))
+		}
+
+		switch key {
+		case "id":
+			fe.ID = value
+		case "before":
+			fe.BeforeIDs = append(fe.BeforeIDs, value)
+		case "after":
+			fe.AfterIDs = append(fe.AfterIDs, value)
+		case "select":
+			var err error
+			fe.Selector, err = tagsyntax.ParseLabels(value)
+
+			if err != nil {
+				return false, fe.error(fmt.Errorf("%w: selector parse failed; filterEntryPath=%q option=%q: %v",
+					depinj.ErrBadFilterEntry, fe.Path, option, err))
+			}
+		default:
+			// unknown options are ignored, as depinj does.
+		}
+	}
+
+	return true, nil
+}
+
+// MethodSelector returns the selector expression of the filter method, without
+// the operand, e.g. `.Base.ModifyDB`.
+func (fe *FilterEntry) MethodSelector() string {
+	var buffer bytes.Buffer
+
+	for _, field := range fe.MethodPath {
+		buffer.WriteByte('.')
+		buffer.WriteString(field.Name())
+	}
+
+	buffer.WriteByte('.')
+	buffer.WriteString(fe.Method.Name())
+	return buffer.String()
+}
+
+// IsWildcard returns true if the filter entry may filter more than one export
+// entry, by a selector or a ref id pattern.
+func (fe *FilterEntry) IsWildcard() bool {
+	return len(fe.Selector) >= 1 || strings.ContainsAny(fe.RefID, "*?[")
+}
+
+func (fe *FilterEntry) hasID(id string) bool {
+	return id == fe.Path || (fe.ID != "" && id == fe.ID)
+}
+
+func (fe *FilterEntry) resolve1(context *resolution12Context, pod *Pod) error {
+	fe.Pod = pod
+	fe.Seq = context.NextFilterEntrySeq()
+	refLink, err := fe.resolveRefLink(context, pod)
+
+	if err != nil {
+		return err
+	}
+
+	if refLink != "" {
+		return fe.error(fmt.Errorf("%w: unresolvable ref link; filterEntryPath=%q refLink=%q",
+			depinj.ErrBadFilterEntry, fe.Path, refLink))
+	}
+
+	return nil
+}
+
+func (fe *FilterEntry) resolve2(context *resolution12Context) error {
+	fieldType := fe.FieldType.Underlying().(*types.Pointer).Elem()
+
+	if fe.IsWildcard() {
+		for _, exportEntry := range context.ExportEntries() {
+			if !types.Identical(exportEntry.FieldType, fieldType) || !tagsyntax.HasLabels(exportEntry.Labels, fe.Selector) {
+				continue
+			}
+
+			if fe.RefID != "" {
+				if !tagsyntax.MatchRefID(fe.RefID, exportEntry.RefID) {
+					continue
+				}
+			}
+
+			fe.attachTo(exportEntry)
+		}
+
+		return nil
+	}
+
+	var exportEntry *ExportEntry
+
+	if fe.RefID == "" {
+		var ok bool
+		exportEntry, ok = context.FindExportEntryByFieldType(fieldType)
+
+		if !ok {
+			return fe.error(fmt.Errorf("%w: export entry not found by field type; filterEntryPath=%q fieldType=%q",
+				depinj.ErrBadFilterEntry, fe.Path, typeString(fieldType)))
+		}
+	} else {
+		var ok bool
+		exportEntry, ok = context.FindExportEntryByRefID(fe.RefID)
+
+		if !ok {
+			return fe.error(fmt.Errorf("%w: export entry not found by ref id; filterEntryPath=%q refID=%q",
+				depinj.ErrBadFilterEntry, fe.Path, fe.RefID))
+		}
+
+		if expectedFieldType := types.NewPointer(exportEntry.FieldType); !types.Identical(fe.FieldType, expectedFieldType) {
+			return fe.error(fmt.Errorf("%w: field type mismatch; filterEntryPath=%q fieldType=%q expectedFieldType=%q exportEntryPath=%q",
+				depinj.ErrBadFilterEntry, fe.Path, typeString(fe.FieldType), typeString(expectedFieldType), exportEntry.Path))
+		}
+	}
+
+	fe.attachTo(exportEntry)
+	return nil
+}
+
+func (fe *FilterEntry) attachTo(exportEntry *ExportEntry) {
+	fe.ExportEntries = append(fe.ExportEntries, exportEntry)
+	exportEntry.FilterEntries = append(exportEntry.FilterEntries, fe)
+}
+
+// ConfigEntry represents a config entry.
+type ConfigEntry struct {
+	Entry
+
+	// ParseField
+	Key          string
+	DefaultValue *string
+	IsOptional   bool
+}
+
+func (ce *ConfigEntry) parseField(fieldInfo *fieldInfo) (bool, error) {
+	args, ok := ce.Entry.parseField(fieldInfo, "config")
+
+	if !ok {
+		return false, nil
+	}
+
+	if !fieldInfo.Field.Exported() {
+		return false, ce.error(fmt.Errorf("%w: field unexported; configEntryPath=%q",
+			depinj.ErrBadConfigEntry, ce.Path))
+	}
+
+	ce.Key = args[0]
+
+	if ce.Key == "" {
+		return false, ce.error(fmt.Errorf("%w: missing argument `key`; configEntryPath=%q",
+			depinj.ErrBadConfigEntry, ce.Path))
+	}
+
+	for i := 1; i < len(args); i++ {
+		arg := args[i]
+
+		if defaultValue := strings.TrimPrefix(arg, "default="); defaultValue != arg {
+			// the default value may contain commas, e.g. `default=a,b,c`
+			defaultValue = strings.Join(append([]string{defaultValue}, args[i+1:]...), ",")
+			ce.DefaultValue = &defaultValue
+			break
+		}
+
+		if arg == "optional" {
+			ce.IsOptional = true
+			continue
+		}
+
+		return false, ce.error(fmt.Errorf("%w: unknown argument; configEntryPath=%q arg=%q",
+			depinj.ErrBadConfigEntry, ce.Path, arg))
+	}
+
+	return true, nil
+}
+
+// Error is the error returned by ParsePod and Resolve, which carries the
+// position of the offending struct field or pod structure.
+type Error struct {
+	Pos token.Pos
+	Err error
+}
+
+// Error implements error.Error.
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// ErrUnsupported is the error returned when a pod can't be resolved statically.
+var ErrUnsupported = errors.New("depinj: unsupported statically")
+
+type resolution12Context struct {
+	refLinkResolver       depinj.RefLinkResolver
+	fieldType2ExportEntry typeutil.Map
+	refID2ExportEntry     map[string]*ExportEntry
+	exportEntries         []*ExportEntry
+	filterEntrySeq        int
+}
+
+func (rc *resolution12Context) Init(refLinkResolver depinj.RefLinkResolver) *resolution12Context {
+	rc.refLinkResolver = refLinkResolver
+	rc.refID2ExportEntry = make(map[string]*ExportEntry)
+	return rc
+}
+
+func (rc *resolution12Context) ResolveRefLink(refLink string) (string, bool) {
+	if rc.refLinkResolver == nil {
+		return "", false
+	}
+
+	return rc.refLinkResolver.ResolveRefLink(refLink)
+}
+
+func (rc *resolution12Context) NextFilterEntrySeq() int {
+	rc.filterEntrySeq++
+	return rc.filterEntrySeq
+}
+
+func (rc *resolution12Context) AddExportEntryByFieldType(exportEntry *ExportEntry, fieldType types.Type) (*ExportEntry, bool) {
+	if addedExportEntry, ok := rc.fieldType2ExportEntry.At(fieldType).(*ExportEntry); ok {
+		return addedExportEntry, false
+	}
+
+	rc.fieldType2ExportEntry.Set(fieldType, exportEntry)
+	rc.exportEntries = append(rc.exportEntries, exportEntry)
+	return nil, true
+}
+
+func (rc *resolution12Context) AddExportEntryByRefID(exportEntry *ExportEntry, refID string) (*ExportEntry, bool) {
+	if addedExportEntry, ok := rc.refID2ExportEntry[refID]; ok {
+		return addedExportEntry, false
+	}
+
+	rc.refID2ExportEntry[refID] = exportEntry
+	rc.exportEntries = append(rc.exportEntries, exportEntry)
+	return nil, true
+}
+
+func (rc *resolution12Context) ExportEntries() []*ExportEntry {
+	return rc.exportEntries
+}
+
+func (rc *resolution12Context) FindExportEntryByFieldType(fieldType types.Type) (*ExportEntry, bool) {
+	exportEntry, ok := rc.fieldType2ExportEntry.At(fieldType).(*ExportEntry)
+	return exportEntry, ok
+}
+
+func (rc *resolution12Context) FindExportEntryByRefID(refID string) (*ExportEntry, bool) {
+	exportEntry, ok := rc.refID2ExportEntry[refID]
+	return exportEntry, ok
+}
+
+type resolution3Context struct {
+	stack     []resolution3StackFrame
+	podStates map[*Pod]resolution3PodState
+	pods      []*Pod
+}
+
+func (rc *resolution3Context) Init() *resolution3Context {
+	rc.podStates = make(map[*Pod]resolution3PodState)
+	return rc
+}
+
+func (rc *resolution3Context) EnterPod(pod *Pod, targetEntry *Entry) resolution3PodState {
+	rc.stack = append(rc.stack, resolution3StackFrame{
+		Pod:         pod,
+		TargetEntry: targetEntry,
+	})
+
+	podState := rc.podStates[pod]
+	rc.podStates[pod] = resolution3PodEntered
+	return podState
+}
+
+func (rc *resolution3Context) LeavePod() {
+	pod := rc.stack[len(rc.stack)-1].Pod
+	rc.stack = rc.stack[:len(rc.stack)-1]
+	rc.podStates[pod] = resolution3PodLeft
+}
+
+func (rc *resolution3Context) SetActiveEntry(activeEntry *Entry) {
+	rc.stack[len(rc.stack)-1].ActiveEntry = activeEntry
+}
+
+func (rc *resolution3Context) DumpStack() string {
+	var stackTraceBuffer bytes.Buffer
+
+	for i, stackFrame := range rc.stack {
+		if i >= 1 {
+			stackTraceBuffer.WriteString(" ==> ")
+		}
+
+		f1 := stackFrame.TargetEntry != nil
+		f2 := stackFrame.ActiveEntry != nil
+
+		if f1 {
+			stackTraceBuffer.WriteString(stackFrame.TargetEntry.Path)
+		}
+
+		if f1 && f2 {
+			stackTraceBuffer.WriteString(" ... ")
+		}
+
+		if f2 {
+			stackTraceBuffer.WriteString(stackFrame.ActiveEntry.Path)
+		}
+	}
+
+	return stackTraceBuffer.String()
+}
+
+// Error wraps the given error with the position of the entry through which
+// the pod on the top of the stack is entered.
+func (rc *resolution3Context) Error(err error) error {
+	if n := len(rc.stack); n >= 2 {
+		if activeEntry := rc.stack[n-2].ActiveEntry; activeEntry != nil {
+			return activeEntry.error(err)
+		}
+	}
+
+	return rc.stack[len(rc.stack)-1].Pod.error(err)
+}
+
+func (rc *resolution3Context) AppendPod(pod *Pod) {
+	rc.pods = append(rc.pods, pod)
+}
+
+func (rc *resolution3Context) Pods() []*Pod {
+	return rc.pods
+}
+
+type resolution3StackFrame struct {
+	Pod         *Pod
+	TargetEntry *Entry
+	ActiveEntry *Entry
+}
+
+type resolution3PodState int
+
+const (
+	resolution3PodUnvisited resolution3PodState = iota
+	resolution3PodEntered
+	resolution3PodLeft
+)
+
+var errorType = types.Universe.Lookup("error").Type()
+
+func filterKindOf(signature *types.Signature, valueType types.Type) (FilterKind, bool) {
+	if signature.Variadic() {
+		return 0, false
+	}
+
+	params, results := signature.Params(), signature.Results()
+
+	if params.Len() == 0 || !isNamed(params.At(0).Type(), "context", "Context") {
+		return 0, false
+	}
+
+	if results.Len() == 1 && types.Identical(results.At(0).Type(), errorType) {
+		switch {
+		case params.Len() == 1:
+			return FilterPlain, true
+		case params.Len() == 2 && isFilterTarget(params.At(1).Type()):
+			return FilterTargeted, true
+		}
+
+		return 0, false
+	}
+
+	if results.Len() != 2 || !types.Identical(results.At(0).Type(), valueType) ||
+		!types.Identical(results.At(1).Type(), errorType) {
+		return 0, false
+	}
+
+	switch {
+	case params.Len() == 2 && types.Identical(params.At(1).Type(), valueType):
+		return FilterDecorator, true
+	case params.Len() == 3 && isFilterTarget(params.At(1).Type()) && types.Identical(params.At(2).Type(), valueType):
+		return FilterTargetedDecorator, true
+	}
+
+	return 0, false
+}
+
+func lookUpMethod(methodSet *types.MethodSet, methodName string) *types.Func {
+	if !token.IsExported(methodName) {
+		return nil
+	}
+
+	selection := methodSet.Lookup(nil, methodName)
+
+	if selection == nil {
+		return nil
+	}
+
+	return selection.Obj().(*types.Func)
+}
+
+// hasMethod reports whether the method set has the method with the given
+// parameter and result types, a nil parameter type stands for context.Context.
+func hasMethod(methodSet *types.MethodSet, methodName string, paramTypes []types.Type, resultTypes []types.Type) bool {
+	method := lookUpMethod(methodSet, methodName)
+
+	if method == nil {
+		return false
+	}
+
+	signature := method.Type().(*types.Signature)
+	params, results := signature.Params(), signature.Results()
+
+	if signature.Variadic() || params.Len() != len(paramTypes) || results.Len() != len(resultTypes) {
+		return false
+	}
+
+	for i, paramType := range paramTypes {
+		if paramType == nil {
+			if !isNamed(params.At(i).Type(), "context", "Context") {
+				return false
+			}
+		} else if !types.Identical(params.At(i).Type(), paramType) {
+			return false
+		}
+	}
+
+	for i, resultType := range resultTypes {
+		if !types.Identical(results.At(i).Type(), resultType) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isFilterTarget(typ types.Type) bool {
+	return isNamed(typ, "github.com/roy2220/depinj", "FilterTarget")
+}
+
+func isNamed(typ types.Type, pkgPath string, name string) bool {
+	named, ok := typ.(*types.Named)
+
+	if !ok {
+		return false
+	}
+
+	object := named.Obj()
+	return object.Pkg() != nil && object.Pkg().Path() == pkgPath && object.Name() == name
+}
+
+func isStructure(typ types.Type) bool {
+	_, ok := typ.Underlying().(*types.Struct)
+	return ok
+}
+
+// typeString returns the string of the given type as reflect.Type.String does,
+// e.g. `*app.Server`.
+func typeString(typ types.Type) string {
+	return types.TypeString(typ, (*types.Package).Name)
+}
+
+// signatureString returns the string of the given signature without the
+// parameter names, as reflect.Type.String does.
+func signatureString(signature *types.Signature) string {
+	var buffer bytes.Buffer
+	buffer.WriteString("func(")
+	params := signature.Params()
+
+	for i := 0; i < params.Len(); i++ {
+		if i >= 1 {
+			buffer.WriteString(", ")
+		}
+
+		if signature.Variadic() && i == params.Len()-1 {
+			buffer.WriteString("...")
+			buffer.WriteString(typeString(params.At(i).Type().(*types.Slice).Elem()))
+		} else {
+			buffer.WriteString(typeString(params.At(i).Type()))
+		}
+	}
+
+	buffer.WriteByte(')')
+
+	switch results := signature.Results(); results.Len() {
+	case 0:
+	case 1:
+		buffer.WriteByte(' ')
+		buffer.WriteString(typeString(results.At(0).Type()))
+	default:
+		buffer.WriteString(" (")
+
+		for i := 0; i < results.Len(); i++ {
+			if i >= 1 {
+				buffer.WriteString(", ")
+			}
+
+			buffer.WriteString(typeString(results.At(i).Type()))
+		}
+
+		buffer.WriteByte(')')
+	}
+
+	return buffer.String()
+}
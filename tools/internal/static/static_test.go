@@ -0,0 +1,159 @@
+package static_test
+
+import (
+	"errors"
+	"go/types"
+	"reflect"
+	"testing"
+
+	"github.com/roy2220/depinj"
+	"github.com/roy2220/depinj/tools/internal/static"
+	"github.com/roy2220/depinj/tools/internal/static/testdata/pods"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/tools/go/packages"
+)
+
+func TestResolve(t *testing.T) {
+	pkg := loadPackage(t, "github.com/roy2220/depinj/tools/internal/static/testdata/pods")
+
+	for _, tc := range pods.TestCases {
+		tc := tc
+
+		t.Run(tc.Name, func(t *testing.T) {
+			expectedPodTypes, expectedErr := resolveAtRuntime(tc.Pods, tc.RefLinks)
+			var staticPods []*static.Pod
+			var err error
+
+			for _, rawPod := range tc.Pods {
+				var staticPod *static.Pod
+				staticPod, err = static.ParsePod(podType(pkg, rawPod))
+
+				if err != nil {
+					break
+				}
+
+				staticPods = append(staticPods, staticPod)
+			}
+
+			var podTypes []string
+
+			if err == nil {
+				var refLinkResolver depinj.RefLinkResolver
+
+				if tc.RefLinks != nil {
+					refLinkResolver = tc.RefLinks
+				}
+
+				staticPods, err = static.Resolve(staticPods, refLinkResolver)
+
+				for _, staticPod := range staticPods {
+					podTypes = append(podTypes, staticPod.String())
+				}
+			}
+
+			if expectedErr == nil {
+				if assert.NoError(t, err) {
+					assert.Equal(t, expectedPodTypes, podTypes)
+				}
+
+				return
+			}
+
+			if assert.Error(t, err) {
+				assert.Equal(t, expectedErr.Error(), err.Error())
+				assert.True(t, errors.Is(err, errors.Unwrap(expectedErr)) || errors.Is(err, expectedErr), "%v", err)
+				var staticErr *static.Error
+
+				if assert.True(t, errors.As(err, &staticErr)) {
+					assert.True(t, staticErr.Pos.IsValid())
+				}
+			}
+		})
+	}
+}
+
+func TestParsePod(t *testing.T) {
+	pkg := loadPackage(t, "github.com/roy2220/depinj/tools/internal/static/testdata/pods")
+	pod, err := static.ParsePod(podType(pkg, &pods.F4{}))
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, "*pods.F4", pod.String())
+	assert.Len(t, pod.ImportEntries, 0)
+	assert.Len(t, pod.ExportEntries, 0)
+
+	if assert.Len(t, pod.FilterEntries, 1) {
+		filterEntry := pod.FilterEntries[0]
+		assert.Equal(t, "pods.F4.Foo", filterEntry.Path)
+		assert.Equal(t, "F?o", filterEntry.RawRefID)
+		assert.Equal(t, static.FilterTargetedDecorator, filterEntry.Kind)
+		assert.Equal(t, 0, filterEntry.Priority)
+		assert.Equal(t, []string{"f1"}, filterEntry.BeforeIDs)
+		assert.Equal(t, ".Filter", filterEntry.MethodSelector())
+	}
+
+	_, err = static.ParsePod(types.Typ[types.Int])
+	assert.True(t, errors.Is(err, depinj.ErrInvalidPod), "%v", err)
+}
+
+func loadPackage(t *testing.T, pkgPath string) *types.Package {
+	pkgs, err := packages.Load(&packages.Config{Mode: packages.LoadAllSyntax}, pkgPath)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if packages.PrintErrors(pkgs) >= 1 {
+		t.FailNow()
+	}
+
+	return pkgs[0].Types
+}
+
+func podType(pkg *types.Package, rawPod depinj.Pod) types.Type {
+	typeName := reflect.TypeOf(rawPod).Elem().Name()
+	return types.NewPointer(pkg.Scope().Lookup(typeName).Type())
+}
+
+func resolveAtRuntime(rawPods []depinj.Pod, refLinks depinj.RefLinkMap) ([]string, error) {
+	var pp depinj.PodPool
+
+	if refLinks != nil {
+		pp.AddRefLinkResolver(refLinks)
+	}
+
+	for _, rawPod := range rawPods {
+		if err := pp.AddPod(rawPod); err != nil {
+			return nil, err
+		}
+	}
+
+	graph, err := pp.Graph()
+
+	if err != nil {
+		return nil, err
+	}
+
+	var podTypes []string
+
+	for _, graphPod := range graph.Pods {
+		podTypes = append(podTypes, graphPod.Type)
+	}
+
+	return podTypes, nil
+}
+
+func TestCheckPod(t *testing.T) {
+	pkg := loadPackage(t, "github.com/roy2220/depinj/tools/internal/static/testdata/pods")
+	errs := static.CheckPod(podType(pkg, &pods.H1{}))
+
+	if assert.Len(t, errs, 2) {
+		assert.True(t, errors.Is(errs[0], depinj.ErrBadImportEntry), "%v", errs[0])
+		assert.True(t, errors.Is(errs[1], depinj.ErrBadFilterEntry), "%v", errs[1])
+	}
+
+	errs = static.CheckPod(podType(pkg, &pods.A1{}))
+	assert.Len(t, errs, 0)
+}
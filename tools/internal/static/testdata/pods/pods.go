@@ -0,0 +1,387 @@
+// Package pods provides the pods for the tests of package static, which are
+// resolved both statically and by depinj.PodPool.
+package pods
+
+import (
+	"context"
+	"sync"
+
+	"github.com/roy2220/depinj"
+)
+
+// TestCase represents a test case.
+type TestCase struct {
+	Name     string
+	Pods     []depinj.Pod
+	RefLinks depinj.RefLinkMap
+}
+
+// TestCases are the test cases.
+var TestCases = []TestCase{
+	{Name: "Chain", Pods: []depinj.Pod{&A3{}, &A2{}, &A1{}, &F1{}, &F2{}, &F3{}, &F4{}}},
+	{Name: "DoubleStar", Pods: []depinj.Pod{&F5{}, &A2{}, &A1{}}},
+	{Name: "Selector", Pods: []depinj.Pod{&L3{}, &L1{}, &L2{}}},
+	{Name: "RefLink", Pods: []depinj.Pod{&R2{}, &A1{}}, RefLinks: depinj.RefLinkMap{"Foo": "Foo"}},
+	{Name: "UnresolvableRefLink", Pods: []depinj.Pod{&R2{}, &A1{}}},
+	{Name: "DuplicateFieldType", Pods: []depinj.Pod{&A1{}, &B1{}}},
+	{Name: "DuplicateRefID", Pods: []depinj.Pod{&A1{}, &B2{}}},
+	{Name: "ImportNotFoundByRefID", Pods: []depinj.Pod{&A2{}}},
+	{Name: "ImportNotFoundByFieldType", Pods: []depinj.Pod{&B3{}}},
+	{Name: "ImportFieldTypeMismatch", Pods: []depinj.Pod{&A1{}, &B4{}}},
+	{Name: "FilterFieldTypeMismatch", Pods: []depinj.Pod{&A1{}, &B5{}}},
+	{Name: "AmbiguousLabels", Pods: []depinj.Pod{&L1{}, &L2{}, &L4{}}},
+	{Name: "CircularDependency", Pods: []depinj.Pod{&C1{}, &C2{}}},
+	{Name: "FilterCircularConstraint", Pods: []depinj.Pod{&A1{}, &C3{}, &C4{}}},
+	{Name: "UnexportedField", Pods: []depinj.Pod{&E1{}}},
+	{Name: "NonPointerFilter", Pods: []depinj.Pod{&E2{}}},
+	{Name: "MissingMethod", Pods: []depinj.Pod{&E3{}}},
+	{Name: "MethodTypeMismatch", Pods: []depinj.Pod{&E4{}}},
+	{Name: "MissingPriority", Pods: []depinj.Pod{&E5{}}},
+	{Name: "BadPriority", Pods: []depinj.Pod{&E6{}}},
+	{Name: "BadSelector", Pods: []depinj.Pod{&E7{}}},
+	{Name: "UnknownOption", Pods: []depinj.Pod{&A4{}, &A1{}, &F6{}}},
+	{Name: "BadLabels", Pods: []depinj.Pod{&E8{}}},
+	{Name: "BadEmbeddedField", Pods: []depinj.Pod{&E9{}}},
+	{Name: "NoEntry", Pods: []depinj.Pod{&E10{}}},
+	{Name: "BadConfigEntry", Pods: []depinj.Pod{&E11{}}},
+	{Name: "Inline", Pods: []depinj.Pod{&I2{}, &I1{}}},
+	{Name: "EmbeddedPointerWithoutEntry", Pods: []depinj.Pod{&I3{}, &I1{}}},
+	{Name: "UnknownDepinjTag", Pods: []depinj.Pod{&E12{}}},
+	{Name: "NonStructureInlineField", Pods: []depinj.Pod{&E13{}}},
+	{Name: "UnexportedInlineField", Pods: []depinj.Pod{&E14{}}},
+	{Name: "RecursiveStructure", Pods: []depinj.Pod{&E15{}}},
+}
+
+// A1 is a pod.
+type A1 struct {
+	depinj.DummyPod
+	Foo int `export:"Foo"`
+}
+
+// A2 is a pod.
+type A2 struct {
+	depinj.DummyPod
+	Foo int    `import:"Foo"`
+	Bar string `export:""`
+}
+
+// A3 is a pod.
+type A3 struct {
+	depinj.DummyPod
+	Bar string `import:""`
+}
+
+// A4 is a pod.
+type A4 struct {
+	depinj.DummyPod
+	Foo int `import:"Foo,optional"`
+	Bar int `export:"Bar,deprecated"`
+}
+
+// F1 is a pod.
+type F1 struct {
+	depinj.DummyPod
+	Foo *int `filter:"Foo,Filter,0,id=f1"`
+}
+
+// Filter is a filter method.
+func (F1) Filter(context.Context) error { return nil }
+
+// F2 is a pod.
+type F2 struct {
+	depinj.DummyPod
+	Foo *int `filter:"Foo,Filter,1,after=f1"`
+}
+
+// Filter is a filter method.
+func (F2) Filter(context.Context, depinj.FilterTarget) error { return nil }
+
+// F3 is a pod.
+type F3 struct {
+	depinj.DummyPod
+	F3Filters
+}
+
+// F3Filters is embedded by F3.
+type F3Filters struct {
+	Bar *string `filter:"*,Filter,0"`
+}
+
+// Filter is a filter method.
+func (*F3Filters) Filter(_ context.Context, bar string) (string, error) { return bar, nil }
+
+// F4 is a pod.
+type F4 struct {
+	depinj.DummyPod
+	Foo *int `filter:"F?o,Filter,before=f1"`
+}
+
+// Filter is a filter method.
+func (F4) Filter(_ context.Context, _ depinj.FilterTarget, foo int) (int, error) { return foo, nil }
+
+// F5 is a pod.
+type F5 struct {
+	depinj.DummyPod
+	Foo *int `filter:"**o,Filter,0"`
+}
+
+// Filter is a filter method.
+func (F5) Filter(_ context.Context, _ depinj.FilterTarget, foo int) (int, error) { return foo, nil }
+
+// F6 is a pod.
+type F6 struct {
+	depinj.DummyPod
+	Bar *int `filter:"Bar,Filter,0,extra,x=y"`
+}
+
+// Filter is a filter method.
+func (F6) Filter(_ context.Context, _ depinj.FilterTarget, bar int) (int, error) { return bar, nil }
+
+// L1 is a pod.
+type L1 struct {
+	depinj.DummyPod
+	DB string `export:"primary_db,labels=role:primary;kind:db"`
+}
+
+// L2 is a pod.
+type L2 struct {
+	depinj.DummyPod
+	DB string `export:"replica_db,labels=role:replica;kind:db"`
+}
+
+// L3 is a pod.
+type L3 struct {
+	depinj.DummyPod
+	DB       string  `import:",select=role:replica"`
+	DBFilter *string `filter:",Filter,0,select=kind:db"`
+}
+
+// Filter is a filter method.
+func (L3) Filter(context.Context) error { return nil }
+
+// L4 is a pod.
+type L4 struct {
+	depinj.DummyPod
+	DB string `import:",select=kind:db"`
+}
+
+// R2 is a pod.
+type R2 struct {
+	depinj.DummyPod
+	Foo int `import:"@Foo"`
+}
+
+// B1 is a pod.
+type B1 struct {
+	depinj.DummyPod
+	Foo  int `export:""`
+	Foo2 int `export:""`
+}
+
+// B2 is a pod.
+type B2 struct {
+	depinj.DummyPod
+	Foo int `export:"Foo"`
+}
+
+// B3 is a pod.
+type B3 struct {
+	depinj.DummyPod
+	Foo float64 `import:""`
+}
+
+// B4 is a pod.
+type B4 struct {
+	depinj.DummyPod
+	Foo int64 `import:"Foo"`
+}
+
+// B5 is a pod.
+type B5 struct {
+	depinj.DummyPod
+	Foo *int64 `filter:"Foo,Filter,0"`
+}
+
+// Filter is a filter method.
+func (B5) Filter(context.Context) error { return nil }
+
+// C1 is a pod.
+type C1 struct {
+	depinj.DummyPod
+	Foo int    `import:"Foo"`
+	Bar string `export:"Bar"`
+}
+
+// C2 is a pod.
+type C2 struct {
+	depinj.DummyPod
+	Bar string `import:"Bar"`
+	Foo int    `export:"Foo"`
+}
+
+// C3 is a pod.
+type C3 struct {
+	depinj.DummyPod
+	Foo *int `filter:"Foo,Filter,0,id=c3,before=c4"`
+}
+
+// Filter is a filter method.
+func (C3) Filter(context.Context) error { return nil }
+
+// C4 is a pod.
+type C4 struct {
+	depinj.DummyPod
+	Foo *int `filter:"Foo,Filter,0,id=c4,before=c3"`
+}
+
+// Filter is a filter method.
+func (C4) Filter(context.Context) error { return nil }
+
+// E1 is a pod.
+type E1 struct {
+	depinj.DummyPod
+	foo int `export:""`
+}
+
+// E2 is a pod.
+type E2 struct {
+	depinj.DummyPod
+	Foo int `filter:"Foo,Filter,0"`
+}
+
+// E3 is a pod.
+type E3 struct {
+	depinj.DummyPod
+	Foo *int `filter:"Foo,Filterr,0"`
+}
+
+// Filter is a filter method.
+func (E3) Filter(context.Context) error { return nil }
+
+// E4 is a pod.
+type E4 struct {
+	depinj.DummyPod
+	Foo *int `filter:"Foo,Filter,0"`
+}
+
+// Filter is a filter method.
+func (E4) Filter(context.Context, string) (string, error) { return "", nil }
+
+// E5 is a pod.
+type E5 struct {
+	depinj.DummyPod
+	Foo *int `filter:"Foo,Filter"`
+}
+
+// Filter is a filter method.
+func (E5) Filter(context.Context) error { return nil }
+
+// E6 is a pod.
+type E6 struct {
+	depinj.DummyPod
+	Foo *int `filter:"Foo,Filter,high"`
+}
+
+// Filter is a filter method.
+func (E6) Filter(context.Context) error { return nil }
+
+// E7 is a pod.
+type E7 struct {
+	depinj.DummyPod
+	Foo int `import:"Foo,select=x"`
+}
+
+// E8 is a pod.
+type E8 struct {
+	depinj.DummyPod
+	Foo int `export:"Foo,labels=role"`
+}
+
+// E9 is a pod.
+type E9 struct {
+	depinj.DummyPod
+	E7
+}
+
+// E10 is a pod.
+type E10 struct {
+	depinj.DummyPod
+	Foo int
+}
+
+// E11 is a pod.
+type E11 struct {
+	depinj.DummyPod
+	Foo int `config:",optional"`
+}
+
+// E12 is a pod.
+type E12 struct {
+	depinj.DummyPod
+	Foo int `depinj:"flatten"`
+}
+
+// E13 is a pod.
+type E13 struct {
+	depinj.DummyPod
+	Foo int `depinj:"inline"`
+}
+
+// E14 is a pod.
+type E14 struct {
+	depinj.DummyPod
+	deps I2Deps `depinj:"inline"`
+}
+
+// E15 is a pod.
+type E15 struct {
+	depinj.DummyPod
+	*E15
+}
+
+// G1 is a pod.
+type G1 struct {
+	depinj.DummyPod
+	Port int `config:"port,default=8080"`
+}
+
+// H1 is a pod.
+type H1 struct {
+	depinj.DummyPod
+	Foo int `import:"Foo,select=x"`
+	Bar int `filter:"Bar,Filter,0"`
+}
+
+// I1 is a pod.
+type I1 struct {
+	depinj.DummyPod
+	*I1Base
+}
+
+// I1Base is the base of I1.
+type I1Base struct {
+	Foo int `export:"Foo"`
+}
+
+// I2 is a pod.
+type I2 struct {
+	depinj.DummyPod
+	Deps I2Deps `depinj:"inline"`
+}
+
+// I2Deps is the dependencies of I2.
+type I2Deps struct {
+	Foo int `import:"Foo"`
+}
+
+// I3 is a pod.
+type I3 struct {
+	depinj.DummyPod
+	*sync.Mutex
+	*i3Helper
+	Foo int `import:"Foo"`
+}
+
+type i3Helper struct {
+	Bar int
+}